@@ -5,19 +5,37 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/openshift-scale/perf-analyzer/pkg/result"
+	"github.com/openshift-scale/perf-analyzer/pkg/stats"
 )
 
 var oldFile, newFile string
 var stdDev float64
+var stat string
+var strictTags bool
+var ignoreTags string
+var requireTags = result.Tags{}
 var procAlias map[string]string
+var maxGrowth, minR2 float64
+var growthResources string
 
 func initFlags() {
 	flag.StringVar(&oldFile, "old", "", "Previous run summary")
 	flag.StringVar(&newFile, "new", "", "New run summary")
 	flag.Float64Var(&stdDev, "stddev", 0.05, "Float percentage standard deviation for result tolerance (0.05 = 5%)")
+	flag.StringVar(&stat, "stat", "p95", "Statistic to compare: min, mean, median, max, stddev, variance, cv, time-avg, integral, slope, r2, trend-end, spikes, spike-seconds, above-seconds, change-points or a percentile such as p99")
+	flag.Float64Var(&maxGrowth, "max-growth", 0.01, "Flag results of the new run growing faster than this fraction of their mean per hour (0.01 = 1%/h)")
+	flag.Float64Var(&minR2, "min-r2", 0.5, "Only flag growth whose trend fits with at least this R2")
+	flag.StringVar(&growthResources, "growth-resources", "memory_usage_resident_set_size", "Comma-separated globs of the resources checked for growth")
+	flag.Var(requireTags, "tag", "key=value tag both runs must have, ie. platform=aws (repeatable)")
+	flag.BoolVar(&strictTags, "strict-tags", false, "Refuse to compare runs whose tags differ instead of warning")
+	flag.StringVar(&ignoreTags, "ignore-tags", "", "Comma-separated tag keys expected to differ between the runs, ie. ocp,build")
 	flag.Parse()
 }
 
@@ -54,26 +72,122 @@ func main() {
 		return
 	}
 
+	printProvenance("old", oldFile, oldRun.Provenance)
+	printProvenance("new", newFile, newRun.Provenance)
+	if oldRun.Provenance != nil && newRun.Provenance != nil && percentileMethod(oldRun.Provenance) != percentileMethod(newRun.Provenance) {
+		fmt.Printf("Warning: percentiles were estimated differently, old: %s => new: %s\n", percentileMethod(oldRun.Provenance), percentileMethod(newRun.Provenance))
+	}
+	if oldRun.Provenance != nil && newRun.Provenance != nil && oldRun.Provenance.SteadyOnly != newRun.Provenance.SteadyOnly {
+		fmt.Printf("Warning: only one run was limited to its steady state, old: %t => new: %t\n", oldRun.Provenance.SteadyOnly, newRun.Provenance.SteadyOnly)
+	}
+	printRunInfo("old", oldRun.Run)
+	printRunInfo("new", newRun.Run)
+	if !checkTags(oldRun.Tags, newRun.Tags) {
+		return
+	}
+
 	for i := range oldRun.Hosts {
 		k, err := getHostIndex(newRun.Hosts, oldRun.Hosts[i].Kind)
 		if err != nil {
 			fmt.Printf("Error: %s\n", err)
 			continue
 		}
+		oldInfo, newInfo := oldRun.Hosts[i].Info, newRun.Hosts[k].Info
+		if oldInfo != nil && newInfo != nil && !oldInfo.SameHardware(*newInfo) {
+			fmt.Printf("Warning: %s ran on different hardware, old: %s => new: %s\n", oldRun.Hosts[i].Kind, describeHost(oldInfo), describeHost(newInfo))
+		}
+		if oldSteady, newSteady := oldRun.Hosts[i].Steady, newRun.Hosts[k].Steady; oldSteady != nil || newSteady != nil {
+			fmt.Printf("%s steady state, old: %s => new: %s\n", oldRun.Hosts[i].Kind, describeSteady(oldSteady), describeSteady(newSteady))
+		}
 		for j := range oldRun.Hosts[i].Results {
 			l, err := getResultIndex(newRun.Hosts[k], oldRun.Hosts[i].Results[j])
 			if err != nil {
 				fmt.Printf("Error: %s\n", err)
 				continue
 			}
-			if newRun.Hosts[k].Results[l].Pct95 > oldRun.Hosts[i].Results[j].Pct95*(1+stdDev) ||
-				newRun.Hosts[k].Results[l].Pct95 < oldRun.Hosts[i].Results[j].Pct95*(1-stdDev) {
-				fmt.Printf("%s: Out of spec %s process with %s, old: %.2f => new: %.2f\n", newRun.Hosts[k].Kind, newRun.Hosts[k].Results[l].Kind, newRun.Hosts[k].Results[l].Resource, oldRun.Hosts[i].Results[j].Pct95, newRun.Hosts[k].Results[l].Pct95)
+			// Results without values are written as null, older runs may lack a percentile
+			oldValue, newValue := statValue(oldRun.Hosts[i].Results[j], stat), statValue(newRun.Hosts[k].Results[l], stat)
+			oldMissing, newMissing := math.IsNaN(oldValue), math.IsNaN(newValue)
+			if oldMissing != newMissing {
+				run := "new"
+				if oldMissing {
+					run = "old"
+				}
+				fmt.Printf("%s: %s process with %s%s has no %s value in the %s run\n", newRun.Hosts[k].Kind, newRun.Hosts[k].Results[l].Kind, newRun.Hosts[k].Results[l].Resource, phaseSuffix(newRun.Hosts[k].Results[l].Phase), stat, run)
+				continue
+			}
+			if outOfSpec(oldValue, newValue) {
+				fmt.Printf("%s: Out of spec %s process with %s%s %s, old: %.2f => new: %.2f%s\n", newRun.Hosts[k].Kind, newRun.Hosts[k].Results[l].Kind, newRun.Hosts[k].Results[l].Resource, phaseSuffix(newRun.Hosts[k].Results[l].Phase), stat, oldValue, newValue, verdict(newRun.Hosts[k].Results[l].Direction, oldValue, newValue))
+			}
+		}
+	}
+
+	compareMetrics(oldRun.Metrics, newRun.Metrics)
+
+	resources, err := result.ParseSelectors(growthResources, result.MatchGlob, result.AggregateSum)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid growth resources: %v\n", err)
+		return
+	}
+	checkGrowth(newRun.Hosts, resources)
+}
+
+// statValue returns a statistic of a result, NaN when the result lacks it
+func statValue(r result.ResultType, name string) float64 {
+	value, ok := r.Stat(name)
+	if !ok {
+		return math.NaN()
+	}
+	return value
+}
+
+// checkGrowth flags the results of the selected resources whose trend grows
+// faster than maxGrowth of their mean per hour, ie. a process leaking memory
+func checkGrowth(hosts []result.Host, resources []result.Selector) {
+	for _, h := range hosts {
+		for _, r := range h.Results {
+			if !result.MatchesAny(resources, r.Resource) || math.IsNaN(r.SlopePerHour) || r.Avg == 0 || r.R2 < minR2 {
+				continue
+			}
+			growth := r.SlopePerHour / math.Abs(r.Avg)
+			if growth > maxGrowth {
+				fmt.Printf("%s: Growing %s process with %s%s, %.2f %s per hour (%.1f%% of the mean, R2 %.2f), %.2f at the end\n",
+					h.Kind, r.Kind, r.Resource, phaseSuffix(r.Phase), r.SlopePerHour, r.Unit, growth*100, r.R2, r.TrendEnd)
 			}
 		}
 	}
+}
 
-	// TODO compare metrics?
+// compareMetrics checks every numeric value of the old run metrics against the
+// metric with the same name and type in the new run
+func compareMetrics(old, new []result.Metric) {
+	for _, o := range old {
+		found := false
+		for _, n := range new {
+			if n.Name != o.Name || n.Type != o.Type {
+				continue
+			}
+			found = true
+			keys := make([]string, 0, len(o.Values))
+			for key := range o.Values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				oldValue, ok := o.Float(key)
+				newValue, ok2 := n.Float(key)
+				if !ok || !ok2 {
+					continue
+				}
+				if outOfSpec(oldValue, newValue) {
+					fmt.Printf("Metric %s: Out of spec %s %s, old: %.2f => new: %.2f\n", o.Type, o.Name, key, oldValue, newValue)
+				}
+			}
+		}
+		if !found {
+			fmt.Printf("Error: Metric %s %s not found in new run\n", o.Type, o.Name)
+		}
+	}
 }
 
 func getHostIndex(hostResult []result.Host, kind string) (int, error) {
@@ -86,17 +200,110 @@ func getHostIndex(hostResult []result.Host, kind string) (int, error) {
 }
 
 func getResultIndex(hostResult result.Host, resultItem result.ResultType) (int, error) {
-	for r := range hostResult.Results {
-		if (hostResult.Results[r].Kind == resultItem.Kind ||
-			hostResult.Results[r].Kind == procAlias[resultItem.Kind]) &&
-			hostResult.Results[r].Resource == resultItem.Resource {
-			return r, nil
-		}
+	if r, ok := hostResult.Lookup(resultItem.Key()); ok {
+		return r, nil
+	}
+	alias := resultItem.Key()
+	alias.Kind = procAlias[resultItem.Kind]
+	if r, ok := hostResult.Lookup(alias); ok {
+		return r, nil
 	}
-	return 0, fmt.Errorf("Result index for %s, %s not found", resultItem.Kind, resultItem.Resource)
+	return 0, fmt.Errorf("Result index for %s, %s%s not found", resultItem.Kind, resultItem.Resource, phaseSuffix(resultItem.Phase))
 
 }
 
+// printProvenance tells where the results of a run came from
+func printProvenance(run, file string, p *result.Provenance) {
+	if p == nil {
+		fmt.Printf("%s run %s: no provenance recorded\n", run, file)
+		return
+	}
+	fmt.Printf("%s run %s: scraper %s on %s, created %s, samples %s to %s, %d source files\n", run, file, p.Version, p.SearchDir,
+		p.Created.Format(time.RFC3339), p.FirstSample.Format(time.RFC3339), p.LastSample.Format(time.RFC3339), len(p.Files))
+	fmt.Printf("%s run arguments: %s\n", run, strings.Join(p.Arguments, " "))
+}
+
+// describeSteady returns the steady state of a host and its duration
+func describeSteady(w *result.Window) string {
+	if w == nil {
+		return "not detected"
+	}
+	return fmt.Sprintf("%s to %s (%s)", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339), w.End.Sub(w.Start))
+}
+
+// percentileMethod returns the percentile method of a run, streamed runs used sketches
+// of their accuracy and runs without one used linear
+func percentileMethod(p *result.Provenance) string {
+	if p.Stream {
+		return fmt.Sprintf("sketch (accuracy %g)", p.SketchAccuracy)
+	}
+	if p.PercentileMethod == "" {
+		return string(stats.PercentileLinear)
+	}
+	return p.PercentileMethod
+}
+
+// checkTags makes sure both runs have the required tags and reports tags that
+// differ, it returns false when the runs must not be compared
+func checkTags(old, new result.Tags) bool {
+	fmt.Printf("old run tags: %s\nnew run tags: %s\n", old, new)
+	if !old.Matches(requireTags) || !new.Matches(requireTags) {
+		fmt.Fprintf(os.Stderr, "Runs do not both have the tags %s\n", requireTags)
+		return false
+	}
+	ignored := strings.Split(ignoreTags, ",")
+	diff := old.Without(ignored...).Diff(new.Without(ignored...))
+	if len(diff) == 0 {
+		return true
+	}
+	if strictTags {
+		fmt.Fprintf(os.Stderr, "Refusing to compare runs with different tags: %s\n", strings.Join(diff, ", "))
+		return false
+	}
+	fmt.Printf("Warning: runs have different tags: %s\n", strings.Join(diff, ", "))
+	return true
+}
+
+// printRunInfo shows the pbench metadata of a run
+func printRunInfo(run string, r *result.RunInfo) {
+	if r == nil {
+		return
+	}
+	fmt.Printf("%s run %s (config %s) from %s on %s\n", run, r.Name, r.Config, r.Controller, r.Date)
+}
+
+// describeHost summarises the hardware of a host
+func describeHost(i *result.HostInfo) string {
+	return fmt.Sprintf("%s, %d CPUs, %d kB memory, kernel %s", i.CPUModel, i.Cores, i.MemoryKB, i.Kernel)
+}
+
+// phaseSuffix names the phase of a result, if any
+func phaseSuffix(phase string) string {
+	if phase == "" {
+		return ""
+	}
+	return " during " + phase
+}
+
+// outOfSpec reports whether a new value differs from the old one by more than
+// stdDev of the old magnitude, so negative values are compared the same way
+func outOfSpec(old, new float64) bool {
+	return math.Abs(new-old) > math.Abs(old)*stdDev
+}
+
+// verdict labels a change as a regression or an improvement when the result direction is known
+func verdict(direction string, old, new float64) string {
+	switch {
+	case direction == result.DirectionLower && new > old,
+		direction == result.DirectionHigher && new < old:
+		return " (regression)"
+	case direction == result.DirectionLower && new < old,
+		direction == result.DirectionHigher && new > old:
+		return " (improvement)"
+	}
+	return ""
+}
+
 func readResultJSON(file string) (*result.Result, error) {
 	var res result.Result
 	raw, err := ioutil.ReadFile(file)
@@ -121,8 +328,7 @@ func areHostResultsSimilar(old, new []result.Host) bool {
 			return false
 		}
 		for j := range old[i].Results {
-			if old[i].Results[j].Kind != new[i].Results[j].Kind ||
-				old[i].Results[j].Resource != new[i].Results[j].Resource {
+			if _, ok := new[i].Lookup(old[i].Results[j].Key()); !ok {
 				return false
 			}
 		}
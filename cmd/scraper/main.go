@@ -3,26 +3,62 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/openshift-scale/perf-analyzer/pkg/config"
 	"github.com/openshift-scale/perf-analyzer/pkg/prometheus"
+	"github.com/openshift-scale/perf-analyzer/pkg/result"
 )
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
 func initFlags() (cfg config.ScrapeConfig) {
+	cfg.Tags = result.Tags{}
 	flag.BoolVar(&cfg.EnablePbenchFlag, "pbench", false, "scrape pbench results")
 	flag.BoolVar(&cfg.EnablePrometheusFlag, "prometheus", false, "scrape prometheus endpoint")
 	flag.BoolVar(&cfg.InsecureTLSFlag, "insecure", false, "Trust self-signed HTTP certificates")
 	flag.IntVar(&cfg.DurationFlag, "duration", 30, "Duration of test in integer minutes (used to calculate quest start time)")
+	flag.DurationVar(&cfg.SkipStartFlag, "skip-start", 0, "Duration of warm-up samples to skip at the start of each host's pbench data, ie. 5m")
+	flag.DurationVar(&cfg.SkipEndFlag, "skip-end", 0, "Duration of cool-down samples to skip at the end of each host's pbench data, ie. 2m")
+	flag.StringVar(&cfg.WindowStartFlag, "window-start", "", "Ignore pbench samples before this RFC3339 time")
+	flag.StringVar(&cfg.WindowEndFlag, "window-end", "", "Ignore pbench samples after this RFC3339 time")
+	flag.StringVar(&cfg.TimelineFile, "timeline", "", "CSV file of phase name, start and end to compute per phase results for")
+	flag.BoolVar(&cfg.TimelineMetricsFlag, "timeline-metrics", false, "Use the cluster-loader test durations in result.txt as phases")
+	flag.Var(cfg.Tags, "tag", "key=value tag of the run stored in out.json, ie. ocp=4.2 (repeatable)")
 	flag.StringVar(&cfg.StepFlag, "step", "1m", "Query resolution step width in number of seconds")
 	flag.StringVar(&cfg.TokenFlag, "token", "", "Authorization type + token for endpoint")
 	flag.StringVar(&cfg.UrlFlag, "url", "http://localhost:9090", "URL for prometheus connection")
 	flag.StringVar(&cfg.SearchDir, "i", "/var/lib/pbench-agent/benchmark_result/tools-default/", "pbench run result directory to parse")
+	flag.BoolVar(&cfg.StreamFlag, "stream", false, "Read pbench CSVs row by row and estimate percentiles with sketches, for very long runs")
+	flag.BoolVar(&cfg.SketchFlag, "sketch", false, "Store a mergeable percentile sketch of every result in out.json")
+	flag.Float64Var(&cfg.SketchAccuracyFlag, "sketch-accuracy", 0.01, "Relative accuracy of the percentile sketches (0.01 = 1%)")
+	flag.StringVar(&cfg.HistogramFlag, "histogram", "none", "Histogram of every result in out.json and histograms.csv: linear, log or none")
+	flag.IntVar(&cfg.HistogramBucketsFlag, "histogram-buckets", 20, "Number of buckets of the histograms")
+	flag.StringVar(&cfg.SpecFile, "spec", "", "JSON file of pbench tool CSV specs to add to or replace the built-in ones")
 	flag.StringVar(&cfg.ResultDir, "o", "/tmp/", "output directory for parsed CSV result data")
 	flag.StringVar(&cfg.ProcessString, "proc", "openshift_start_master_api_,openshift_start_master_controll,hyperkube_kubelet_,openshift_start_node_,etcd,dockerd-current_,elasticsearc,prometheus_,systemd_--switched-root,openshift_start_network_,ovs-vswitchd_unix,openshift-router,fluentd,kibana,heapster,crio", "list of processes to gather")
+	flag.StringVar(&cfg.ResourceString, "resources", "*", "Comma-separated globs of the resources to extract, ie. cpu_*,memory_usage_*")
 	flag.StringVar(&cfg.BlockString, "blkdev", "sda-write,sda-read,vda-write,vda-read,xvda-write,xvda-read,xvdb-write,xvdb-read,nvme0n1-write,nvme0n1-read", "List of block devices")
 	flag.StringVar(&cfg.NetString, "netdev", "eth0-rx,eth0-tx", "List of network devices")
+	flag.StringVar(&cfg.MatchFlag, "match", "", "How device and process names match CSV headers: regex, anchored, exact or glob (default anchored for devices, regex for processes)")
+	flag.StringVar(&cfg.OutliersFlag, "outliers", "iqr", "How spikes are detected: iqr, mad or none")
+	flag.Float64Var(&cfg.OutlierKFlag, "outlier-k", 0, "Distance of the outlier fences in IQRs or scaled MADs (default 1.5 for iqr, 3 for mad)")
+	flag.Float64Var(&cfg.ChangePenaltyFlag, "change-penalty", 0, "Cost of a change point of the mean in multiples of the log of the samples, 2 is the BIC, 0 disables change point detection")
+	flag.StringVar(&cfg.SteadyFlag, "steady", "", "Comma-separated globs of the resources or resource/kind results whose steady state is detected, ie. cpu_all/usr")
+	flag.Float64Var(&cfg.SteadyCVFlag, "steady-cv", 0.1, "Highest coefficient of variation of a steady window")
+	flag.DurationVar(&cfg.SteadyLengthFlag, "steady-length", 5*time.Minute, "Length of the sliding windows of the steady state detection")
+	flag.BoolVar(&cfg.SteadyOnlyFlag, "steady-only", false, "Compute statistics only within the steady state of each host")
+	flag.StringVar(&cfg.AboveFlag, "above", "", "Comma-separated resource=value thresholds to measure the time above, ie. cpu_all=80,memory_usage_resident_*=2000000")
+	flag.StringVar(&cfg.PercentileMethodFlag, "percentile-method", "linear", "How percentiles are estimated: linear, inverted-cdf (nearest-rank), averaged-inverted-cdf, closest-observation, interpolated-inverted-cdf, hazen, weibull, median-unbiased, normal-unbiased or midpoint")
+	flag.StringVar(&cfg.PercentilesFlag, "percentiles", "90,99,99.9", "Comma-separated percentiles to compute for each result besides the median and p95")
+	flag.StringVar(&cfg.MissingFlag, "missing", "skip", "What to do with empty or non-numeric CSV samples: skip, zero, fail or interpolate")
+	flag.StringVar(&cfg.AggregateFlag, "aggregate", "sum", "How to combine several headers matching one name: sum, max or separate (override per name with name:max)")
 	flag.Parse()
 
+	cfg.Version = version
+	cfg.Arguments = os.Args[1:]
 	return
 }
 
@@ -41,16 +77,24 @@ func main() {
 
 	if cfg.EnablePbenchFlag {
 		// Create new config structure which will contain all data
-		c := config.NewConfig(cfg)
+		c, err := config.NewConfig(cfg)
+		if err != nil {
+			fmt.Printf("Error creating config: %v\n", err)
+			return
+		}
 
 		// Initialize each host struct
 		c.Init()
 
 		// Process results for each host
-		c.Process()
+		err = c.Process()
+		if err != nil {
+			fmt.Printf("Error processing results: %v\n", err)
+			return
+		}
 
 		// Write CSV and JSON to disk
-		err := c.WriteToDisk()
+		err = c.WriteToDisk()
 		if err != nil {
 			fmt.Printf("Error writing files to disk: %v", err)
 		}
@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"path"
+	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/openshift-scale/perf-analyzer/pkg/result"
+	"github.com/openshift-scale/perf-analyzer/pkg/stats"
 	"github.com/openshift-scale/perf-analyzer/pkg/utils"
-	"github.com/openshift/origin/test/extended/cluster/metrics"
 )
 
 type ScrapeConfig struct {
@@ -18,61 +22,323 @@ type ScrapeConfig struct {
 	EnablePbenchFlag     bool
 	InsecureTLSFlag      bool
 	DurationFlag         int
+	SkipStartFlag        time.Duration
+	SkipEndFlag          time.Duration
+	WindowStartFlag      string
+	WindowEndFlag        string
+	AggregateFlag        string
 	BlockString          string
+	MatchFlag            string
+	MissingFlag          string
 	NetString            string
+	OutliersFlag         string
+	OutlierKFlag         float64
+	AboveFlag            string
+	ChangePenaltyFlag    float64
+	PercentilesFlag      string
+	PercentileMethodFlag string
 	ProcessString        string
+	ResourceString       string
 	ResultDir            string
 	SearchDir            string
+	SketchFlag           bool
+	HistogramFlag        string
+	HistogramBucketsFlag int
+	SketchAccuracyFlag   float64
+	StreamFlag           bool
+	SpecFile             string
+	SteadyFlag           string
+	SteadyCVFlag         float64
+	SteadyLengthFlag     time.Duration
+	SteadyOnlyFlag       bool
+	TimelineFile         string
+	TimelineMetricsFlag  bool
 	StepFlag             string
 	TokenFlag            string
 	UrlFlag              string
+	Tags                 result.Tags
+	// Version and Arguments of the scraper are recorded in the output
+	Version   string
+	Arguments []string
 }
 
 type config struct {
 	searchDir  string
 	resultDir  string
-	fileHeader map[string][]string
+	fileHeader map[string][]result.Selector
+	specs      map[string]ToolSpec
+	window     result.Window
+	skipStart  time.Duration
+	skipEnd    time.Duration
+	phases     []result.Phase
+	useMetrics bool
+	missing    result.MissingPolicy
+	summary    stats.Options
+	stream     bool
+	sketch     bool
+	accuracy   float64
+	histogram  stats.HistogramScale
+	buckets    int
+	outliers   stats.OutlierMethod
+	outlierK   float64
+	above      []threshold
+	penalty    float64
+	steady     []steadySelector
+	steadyCV   float64
+	steadyLen  time.Duration
+	steadyOnly bool
+	provenance result.Provenance
+	run        *result.RunInfo
+	tags       result.Tags
 	hosts      []result.Host
-	Metrics    []metrics.Metrics
+	Metrics    []result.Metric
 	keys       []string
 }
 
 // NewConfig returns a new configuration struct that contains all fields that we need
-func NewConfig(cfg ScrapeConfig) config {
+func NewConfig(cfg ScrapeConfig) (config, error) {
 	var c config
 	if cfg.EnablePbenchFlag {
 		c = config{
 			searchDir:  utils.TrailingSlash(cfg.SearchDir),
 			resultDir:  utils.TrailingSlash(cfg.ResultDir),
-			fileHeader: map[string][]string{},
+			fileHeader: map[string][]result.Selector{},
+			specs:      map[string]ToolSpec{},
+			skipStart:  cfg.SkipStartFlag,
+			skipEnd:    cfg.SkipEndFlag,
+			useMetrics: cfg.TimelineMetricsFlag,
+			tags:       cfg.Tags,
+			provenance: result.Provenance{
+				Version:   cfg.Version,
+				Arguments: cfg.Arguments,
+				SearchDir: cfg.SearchDir,
+				Created:   time.Now().UTC(),
+			},
+		}
+		var err error
+		c.window, err = parseWindow(cfg.WindowStartFlag, cfg.WindowEndFlag)
+		if err != nil {
+			return c, err
+		}
+		if cfg.TimelineFile != "" {
+			c.phases, err = utils.ReadTimeline(cfg.TimelineFile)
+			if err != nil {
+				return c, err
+			}
+		}
+		c.missing, err = result.ParseMissingPolicy(cfg.MissingFlag)
+		if err != nil {
+			return c, err
+		}
+		c.summary, err = SummaryOptions(cfg)
+		if err != nil {
+			return c, err
+		}
+		c.stream, c.sketch, c.accuracy = cfg.StreamFlag, cfg.SketchFlag, cfg.SketchAccuracyFlag
+		if c.stream {
+			c.provenance.Stream, c.provenance.SketchAccuracy = true, c.accuracy
+		} else {
+			c.provenance.PercentileMethod = string(c.summary.Method)
+		}
+		if c.stream || c.sketch {
+			if _, err := stats.NewSketch(c.accuracy); err != nil {
+				return c, err
+			}
+		}
+		c.histogram, err = stats.ParseHistogramScale(cfg.HistogramFlag)
+		if err != nil {
+			return c, err
+		}
+		c.buckets = cfg.HistogramBucketsFlag
+		if c.histogram != stats.HistogramNone && c.buckets < 1 {
+			return c, fmt.Errorf("Invalid number of histogram buckets %d", c.buckets)
+		}
+		c.outliers, err = stats.ParseOutlierMethod(cfg.OutliersFlag)
+		if err != nil {
+			return c, err
+		}
+		c.outlierK = cfg.OutlierKFlag
+		if c.outlierK <= 0 {
+			c.outlierK = stats.DefaultOutlierK(c.outliers)
+		}
+		c.above, err = parseThresholds(cfg.AboveFlag)
+		if err != nil {
+			return c, err
+		}
+		c.penalty = cfg.ChangePenaltyFlag
+		if c.penalty < 0 {
+			return c, fmt.Errorf("Invalid change point penalty %v, expected a positive number or 0 to disable", c.penalty)
+		}
+		c.steady, err = parseSteady(cfg.SteadyFlag)
+		if err != nil {
+			return c, err
+		}
+		c.steadyCV, c.steadyLen, c.steadyOnly = cfg.SteadyCVFlag, cfg.SteadyLengthFlag, cfg.SteadyOnlyFlag
+		if len(c.steady) > 0 && (c.steadyCV <= 0 || c.steadyLen <= 0) {
+			return c, fmt.Errorf("Invalid steady state window %v with a CV of %v, expected positive numbers", c.steadyLen, c.steadyCV)
+		}
+		if c.steadyOnly && len(c.steady) == 0 {
+			return c, fmt.Errorf("Statistics can only be limited to the steady state of resources passed with -steady")
+		}
+		c.provenance.SteadyOnly = c.steadyOnly
+		if c.stream && c.missing == result.MissingInterpolate {
+			return c, fmt.Errorf("Missing values cannot be interpolated when streaming CSV files")
+		}
+		err = c.addHeaders(cfg)
+		if err != nil {
+			return c, err
 		}
-		c.addHeaders(cfg.BlockString, cfg.NetString, cfg.ProcessString)
 	}
 
-	return c
+	return c, nil
 }
 
-// addHeaders will check the command line flags to create the files and headers we're looking for
-func (c *config) addHeaders(blockString, netString, processString string) {
-	blockDevices := strings.Split(blockString, ",")
-	// If no block devices were passed, don't add to search
-	if len(blockDevices) > 0 {
-		c.fileHeader["disk_IOPS.csv"] = blockDevices
+// parseWindow will parse the RFC3339 start and end of the absolute time window, either may be empty
+func parseWindow(start, end string) (w result.Window, err error) {
+	if start != "" {
+		w.Start, err = time.Parse(time.RFC3339, start)
+		if err != nil {
+			return w, fmt.Errorf("Invalid window start: %v", err)
+		}
 	}
+	if end != "" {
+		w.End, err = time.Parse(time.RFC3339, end)
+		if err != nil {
+			return w, fmt.Errorf("Invalid window end: %v", err)
+		}
+	}
+	return w, nil
+}
 
-	netDevices := strings.Split(netString, ",")
-	// If no network devices were passed, don't add to search
-	if len(netDevices) > 0 {
-		c.fileHeader["network_l2_network_packets_sec.csv"] = netDevices
-		c.fileHeader["network_l2_network_Mbits_sec.csv"] = netDevices
+// SummaryOptions will parse the percentiles and the percentile method to compute for each result
+func SummaryOptions(cfg ScrapeConfig) (opts stats.Options, err error) {
+	opts.Percentiles, err = ParsePercentiles(cfg.PercentilesFlag)
+	if err != nil {
+		return opts, err
 	}
+	opts.Method, err = stats.ParsePercentileMethod(cfg.PercentileMethodFlag)
+	return opts, err
+}
 
-	processList := strings.Split(processString, ",")
-	// If no process names were passed, don't add to search
-	if len(processList) > 0 {
-		c.fileHeader["cpu_usage_percent_cpu.csv"] = processList
-		c.fileHeader["memory_usage_resident_set_size.csv"] = processList
+// ParsePercentiles will parse a comma-separated list of percentiles between 0 and 100,
+// 50 and 95 are skipped as every result has them as its Median and Pct95
+func ParsePercentiles(list string) ([]float64, error) {
+	var percentiles []float64
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		percent, err := strconv.ParseFloat(item, 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("Invalid percentile %q, expected a number between 0 and 100", item)
+		}
+		if percent == 50 || percent == 95 {
+			continue
+		}
+		percentiles = append(percentiles, percent)
+	}
+	return percentiles, nil
+}
+
+// threshold is the value above which the time of the matching resources is measured
+type threshold struct {
+	resource result.Selector
+	value    float64
+}
+
+// parseThresholds will parse a comma-separated list of resource glob=value thresholds
+func parseThresholds(list string) ([]threshold, error) {
+	var thresholds []threshold
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		i := strings.LastIndex(item, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("Invalid threshold %q, expected resource=value", item)
+		}
+		value, err := strconv.ParseFloat(item[i+1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid threshold %q: %v", item, err)
+		}
+		resource, err := result.NewSelector(item[:i], result.MatchGlob, result.AggregateSum)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, threshold{resource: resource, value: value})
+	}
+	return thresholds, nil
+}
+
+// threshold returns the first threshold set for a resource
+func (c *config) threshold(key string) (float64, bool) {
+	for _, th := range c.above {
+		if th.resource.Match(key) {
+			return th.value, true
+		}
 	}
+	return 0, false
+}
+
+// addOutliers will record the spikes of a series sampled at times in seconds
+func (c *config) addOutliers(r *result.ResultType, times, values []float64) {
+	if c.outliers == stats.OutliersNone {
+		return
+	}
+	low, high, err := stats.Fences(values, c.outliers, c.outlierK)
+	if err != nil {
+		return
+	}
+	r.SetOutliers(string(c.outliers), low, high, stats.Spikes(times, values, low, high))
+}
+
+// addChangePoints will record the shifts of the mean of a series sampled at times in seconds
+func (c *config) addChangePoints(r *result.ResultType, times, values []float64) {
+	if c.penalty == 0 {
+		return
+	}
+	points, err := stats.ChangePoints(times, values, c.penalty)
+	if err != nil {
+		return
+	}
+	r.SetChangePoints(points)
+}
+
+// addHeaders will merge the tool specs with the spec file and use the command line
+// flags to create the files and headers we're looking for
+func (c *config) addHeaders(cfg ScrapeConfig) error {
+	specs := DefaultSpecs
+	if cfg.SpecFile != "" {
+		userSpecs, err := ReadSpecs(cfg.SpecFile)
+		if err != nil {
+			return err
+		}
+		specs = MergeSpecs(DefaultSpecs, userSpecs)
+	}
+
+	// Resources are selected by name with a list of globs
+	resources, err := result.ParseSelectors(cfg.ResourceString, result.MatchGlob, result.AggregateSum)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		if !result.MatchesAny(resources, spec.resource()) {
+			continue
+		}
+		selectors, err := spec.selectors(cfg)
+		if err != nil {
+			return err
+		}
+		// If no devices or process names were passed, don't add to search
+		if len(selectors) > 0 {
+			c.fileHeader[spec.resource()] = selectors
+			c.specs[spec.resource()] = spec
+		}
+	}
+	return nil
 }
 
 // InitHosts will create the initial host structures with the Kind and ResultDir for each
@@ -86,6 +352,13 @@ func (c *config) Init() {
 		log.Fatal(err)
 	}
 
+	// pbench keeps the run metadata and sysinfo in the parent of the tools directory
+	runDir := path.Dir(path.Clean(c.searchDir))
+	c.run, err = utils.ReadRunInfo(runDir)
+	if err != nil {
+		fmt.Printf("Error reading run metadata: %v\n", err)
+	}
+
 	// Iterate over directory contents
 	for _, item := range dirList {
 		// Match subdirectory that follows our pattern
@@ -95,6 +368,11 @@ func (c *config) Init() {
 				Kind:      Kind[0],
 				ResultDir: c.searchDir + item.Name(),
 			}
+			// sysinfo directories are named after the short or fully qualified host name,
+			// the host result directory is not searched as it holds the raw tool outputs
+			sysinfo, _ := filepath.Glob(filepath.Join(runDir, "sysinfo", "*", Kind[0]))
+			fqdn, _ := filepath.Glob(filepath.Join(runDir, "sysinfo", "*", Kind[0]+".*"))
+			newHost.Info = utils.ReadHostInfo(append(append([]string{filepath.Join(newHost.ResultDir, "sysinfo")}, sysinfo...), fqdn...)...)
 			c.hosts = append(c.hosts, newHost)
 		}
 	}
@@ -110,58 +388,455 @@ func (c *config) addKeys() {
 	sort.Strings(c.keys)
 }
 
-// Process does the bulk of the math reading the CSV raw data and saving results
-func (c *config) Process() {
-	c.addKeys()
-	for i, host := range c.hosts {
-		// Find each raw data CSV
-		for _, key := range c.keys {
-			fileList := utils.FindFile(host.ResultDir, key)
-			// FindFile returns slice, though there should only be one file
-			for _, file := range fileList {
+// csvFile is a pbench CSV read from disk, limited to the host window
+type csvFile struct {
+	path string
+	rows [][]string
+}
+
+// readHost reads every CSV file wanted from a host directory, keyed by resource
+func (c *config) readHost(host result.Host) map[string][]csvFile {
+	files := map[string][]csvFile{}
+	cache := map[string][][]string{}
+	for _, key := range c.keys {
+		// FindFile returns slice, usually there is only one file
+		for _, file := range utils.FindFile(host.ResultDir, c.specs[key].File) {
+			sliceResult, ok := cache[file]
+			if !ok {
 				// Parse file into 2d-string slice
-				sliceResult, err := utils.ReadCSV(file)
+				var err error
+				sliceResult, err = utils.ReadCSV(file)
 				if err != nil {
 					fmt.Printf("Error reading %v: %v\n", file, err)
 					continue
 				}
-				// In a single file we have multiple headers to extract
-				for _, header := range c.fileHeader[key] {
-					// Extract single column of data that we want
-					newResult, err := result.NewSlice(sliceResult, header)
-					if err != nil {
-						//need to keep list of columns same for all types
-						//continue
-						fmt.Printf("NewSlice returned error: %v\n", err)
-					}
+				cache[file] = sliceResult
+				c.addSourceFile(file, len(sliceResult)-1)
+			}
+			files[key] = append(files[key], csvFile{path: file, rows: sliceResult})
+		}
+	}
+	return files
+}
 
-					// Mutate host to add calcuated stats to object
-					c.hosts[i].AddResult(newResult, file, header, key)
+// addSourceFile will record the checksum and number of rows of a CSV file in the provenance
+func (c *config) addSourceFile(file string, rows int) {
+	sum, err := utils.Checksum(file)
+	if err != nil {
+		fmt.Printf("Error computing checksum of %v: %v\n", file, err)
+	}
+	c.provenance.Files = append(c.provenance.Files, result.SourceFile{
+		Path:   c.relativePath(file),
+		SHA256: sum,
+		Rows:   rows,
+	})
+}
 
-				}
+// relativePath returns the path of a file relative to the search directory
+func (c *config) relativePath(file string) string {
+	return strings.TrimPrefix(file, c.searchDir)
+}
+
+// hostWindow trims the configured window by the skip durations, starting from
+// the first and ending at the last sample of all of a host's CSV files
+func (c *config) hostWindow(files map[string][]csvFile) result.Window {
+	var first, last time.Time
+	for _, key := range c.keys {
+		for _, f := range files[key] {
+			start, end, err := result.TimeSpan(f.rows)
+			if err != nil || start.IsZero() {
+				continue
 			}
+			first, last = widenSpan(first, last, start, end)
 		}
 	}
+	return c.spanWindow(first, last)
+}
+
+// widenSpan returns the earliest first and the latest last timestamp
+func widenSpan(first, last, start, end time.Time) (time.Time, time.Time) {
+	if first.IsZero() || start.Before(first) {
+		first = start
+	}
+	if last.IsZero() || end.After(last) {
+		last = end
+	}
+	return first, last
+}
+
+// spanWindow records the first and last sample of a host in the provenance and
+// trims the configured window by the skip durations
+func (c *config) spanWindow(first, last time.Time) result.Window {
+	if first.IsZero() {
+		return c.window
+	}
+	if c.provenance.FirstSample.IsZero() || first.Before(c.provenance.FirstSample) {
+		c.provenance.FirstSample = first.UTC()
+	}
+	if last.After(c.provenance.LastSample) {
+		c.provenance.LastSample = last.UTC()
+	}
+	return c.window.Trim(first, last, c.skipStart, c.skipEnd)
+}
 
-	var m []metrics.Metrics
+// Process does the bulk of the math reading the CSV raw data and saving results
+func (c *config) Process() error {
+	c.addKeys()
+
+	var m []result.Metric
 	err := utils.GetMetrics(c.searchDir, &m)
 	if err != nil {
 		fmt.Printf("Error getting Metrics: %v\n", err)
 	} else {
 		c.Metrics = m
 	}
+	if c.useMetrics {
+		c.phases = append(c.phases, metricPhases(c.Metrics)...)
+	}
+
+	for i, host := range c.hosts {
+		if c.stream {
+			err := c.streamHost(i, host)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		// Read every CSV of the host first so the same window applies to all of them
+		hostFiles := c.readHost(host)
+		window := c.hostWindow(hostFiles)
+		if len(c.steady) > 0 {
+			window = c.steadyWindow(i, c.steadySeries(hostFiles, window), window)
+		}
+		for _, key := range c.keys {
+			err := c.extract(i, key, hostFiles[key], window, "")
+			if err != nil {
+				return err
+			}
+			// Phases get their own results, limited to the host window as well
+			for _, phase := range c.phases {
+				err := c.extract(i, key, hostFiles[key], window.Intersect(phase.Window), phase.Name)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Every host gets the same results in the same order, even with different devices
+	result.Align(c.hosts)
+	return nil
+}
+
+// steadySeries is a series of a host the steady state is detected on, with its times in seconds
+type steadySeries struct {
+	name          string
+	times, values []float64
+}
+
+// steadySeries extracts the columns of the steady state resources of a host within its window
+func (c *config) steadySeries(files map[string][]csvFile, window result.Window) []steadySeries {
+	var series []steadySeries
+	for _, key := range c.keys {
+		if !c.steadyResource(key) {
+			continue
+		}
+		for _, f := range files[key] {
+			rows, err := result.SliceWindow(f.rows, window)
+			if err != nil {
+				continue
+			}
+			for _, header := range c.fileHeader[key] {
+				columns, err := result.NewColumns(rows, header)
+				if err != nil {
+					continue
+				}
+				for _, column := range columns {
+					if !c.isSteady(key, column.Name) || len(column.Timestamps) != len(column.Values) {
+						continue
+					}
+					times := make([]float64, len(column.Timestamps))
+					for j, t := range column.Timestamps {
+						times[j] = seconds(t)
+					}
+					series = append(series, steadySeries{name: key + " " + column.Name, times: times, values: column.Values})
+				}
+			}
+		}
+	}
+	return series
+}
+
+// steadySelector selects the results scanned for the steady state by a glob of
+// their resource and, when kind is set, a glob of their kind
+type steadySelector struct {
+	resource result.Selector
+	kind     *result.Selector
+}
+
+// parseSteady splits a comma-separated list of resource globs, each optionally
+// followed by a kind glob, ie. cpu_all or cpu_*/usr
+func parseSteady(list string) ([]steadySelector, error) {
+	var selectors []steadySelector
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "/", 2)
+		var s steadySelector
+		var err error
+		s.resource, err = result.NewSelector(parts[0], result.MatchGlob, result.AggregateSum)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) == 2 {
+			kind, err := result.NewSelector(parts[1], result.MatchGlob, result.AggregateSum)
+			if err != nil {
+				return nil, err
+			}
+			s.kind = &kind
+		}
+		selectors = append(selectors, s)
+	}
+	return selectors, nil
+}
+
+// isSteady reports whether the steady state is detected on a result, selected
+// by its resource, ie. cpu_all, or by its resource and kind, ie. cpu_all/usr
+func (c *config) isSteady(key, kind string) bool {
+	for _, s := range c.steady {
+		if s.resource.Match(key) && (s.kind == nil || s.kind.Match(kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+// steadyResource reports whether the steady state may be detected on results of a resource
+func (c *config) steadyResource(key string) bool {
+	for _, s := range c.steady {
+		if s.resource.Match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// steadyWindow records the steady state of a host, when every series is
+// steady, and returns the window narrowed to it with -steady-only
+func (c *config) steadyWindow(i int, series []steadySeries, window result.Window) result.Window {
+	if len(series) == 0 {
+		fmt.Printf("No steady state for %s: no samples of the steady state resources\n", c.hosts[i].Kind)
+		return window
+	}
+	var steady result.Window
+	for _, s := range series {
+		start, end, err := stats.SteadyState(s.times, s.values, c.steadyLen.Seconds(), c.steadyCV)
+		if err != nil {
+			fmt.Printf("No steady state for %s: %s %v\n", c.hosts[i].Kind, s.name, err)
+			return window
+		}
+		steady = steady.Intersect(result.Window{Start: result.Epoch(start), End: result.Epoch(end)})
+	}
+	if steady.End.Before(steady.Start) {
+		fmt.Printf("No steady state for %s: the steady state resources are not steady at the same time\n", c.hosts[i].Kind)
+		return window
+	}
+	c.hosts[i].Steady = &steady
+	if c.steadyOnly {
+		return window.Intersect(steady)
+	}
+	return window
+}
+
+// metricPhases turns the cluster-loader test durations into phases
+func metricPhases(m []result.Metric) []result.Phase {
+	var phases []result.Phase
+	for _, metric := range m {
+		start, ok := metric.Time("startTime")
+		seconds, ok2 := metric.Float("testDurationSeconds")
+		if metric.Type != "metrics.TestDuration" || !ok || !ok2 {
+			continue
+		}
+		phases = append(phases, result.Phase{
+			Name:   metric.Name,
+			Window: result.Window{Start: start, End: start.Add(time.Duration(seconds * float64(time.Second)))},
+		})
+	}
+	return phases
+}
+
+// extract will add the results of a resource within a window to a host
+func (c *config) extract(i int, key string, hostFiles []csvFile, window result.Window, phase string) error {
+	var files []csvFile
+	for _, f := range hostFiles {
+		rows, err := result.SliceWindow(f.rows, window)
+		if err != nil {
+			fmt.Printf("Not limiting %v to the time window: %v\n", f.path, err)
+		}
+		files = append(files, csvFile{path: f.path, rows: rows})
+	}
+
+	if c.specs[key].Files != "" {
+		return c.processFiles(i, key, files, phase)
+	}
+	// The headers of a file are checked once, with the results of the whole run
+	claims := result.Claims{}
+	for _, f := range files {
+		// In a single file we have multiple headers to extract
+		for _, header := range c.fileHeader[key] {
+			if phase == "" && len(f.rows) > 0 {
+				claims.Check(f.path, f.rows[0], header)
+			}
+			// Extract the columns of data that we want
+			columns, err := result.NewColumns(f.rows, header)
+			if err != nil {
+				// Hosts missing the column get an empty result when aligned
+				fmt.Printf("NewColumns returned error: %v\n", err)
+				continue
+			}
+
+			for _, column := range columns {
+				err := c.addResult(i, column, []string{f.path}, key, phase)
+				if err != nil {
+					return fmt.Errorf("%v: %v", f.path, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// processFiles combines the columns extracted from every file matched by a spec,
+// ie. the per core mpstat CSVs
+func (c *config) processFiles(i int, key string, files []csvFile, phase string) error {
+	spec := c.specs[key]
+	claims := result.Claims{}
+	for _, header := range c.fileHeader[key] {
+		var columns []result.Column
+		// paths holds the file each column was read from
+		var paths []string
+		for _, f := range files {
+			if phase == "" && len(f.rows) > 0 {
+				claims.Check(f.path, f.rows[0], header)
+			}
+			fileColumns, err := result.NewColumns(f.rows, header)
+			if err != nil {
+				fmt.Printf("NewColumns returned error: %v\n", err)
+				continue
+			}
+			for _, column := range fileColumns {
+				column.Name = spec.fileLabel(f.path) + "-" + column.Name
+				columns = append(columns, column)
+				paths = append(paths, f.path)
+			}
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		if result.Aggregation(spec.Files) != result.AggregateSeparate {
+			column := result.CombineColumns(columns, header.Name, result.Aggregation(spec.Files))
+			err := c.addResult(i, column, paths, key, phase)
+			if err != nil {
+				return fmt.Errorf("%v: %v", spec.File, err)
+			}
+			continue
+		}
+		for j, column := range columns {
+			err := c.addResult(i, column, paths[j:j+1], key, phase)
+			if err != nil {
+				return fmt.Errorf("%v: %v", paths[j], err)
+			}
+		}
+	}
+	return nil
+}
+
+// addResult will apply the missing value policy and add the stats of a column to a host
+// along with the phase and the unit and direction of its spec
+func (c *config) addResult(i int, column result.Column, files []string, key, phase string) error {
+	column, err := result.ApplyMissing(column, c.missing)
+	if err != nil {
+		return err
+	}
+	summary, err := stats.Summarize(column.Values, c.summary)
+	if err != nil {
+		log.Printf("No statistics for %s of %s on %s: %v", column.Name, key, c.hosts[i].Kind, err)
+	}
+	// times stays nil for a column without timestamps
+	var times []float64
+	if len(column.Timestamps) == len(column.Values) {
+		times = make([]float64, len(column.Timestamps))
+		for j, t := range column.Timestamps {
+			times[j] = seconds(t)
+		}
+		integral, err := stats.Integrate(times, column.Values)
+		if err != nil {
+			log.Printf("No integral for %s of %s on %s: %v", column.Name, key, c.hosts[i].Kind, err)
+		} else {
+			summary.SetIntegral(integral)
+		}
+		if trend, err := stats.TheilSen(times, column.Values); err == nil {
+			summary.SetTrend(trend)
+		}
+	}
+	// Mutate host to add calcuated stats to object
+	results := c.hosts[i].AddSummary(summary, files[0], column.Name, key)
+	r := &results[len(results)-1]
+	if c.sketch {
+		r.Sketch, _ = stats.NewSketch(c.accuracy)
+		for _, value := range column.Values {
+			r.Sketch.Add(value)
+		}
+	}
+	if c.histogram != stats.HistogramNone {
+		r.Histogram, _ = stats.HistogramOf(column.Values, c.histogram, c.buckets)
+	}
+	if times != nil {
+		c.addOutliers(r, times, column.Values)
+		c.addChangePoints(r, times, column.Values)
+		if value, ok := c.threshold(key); ok {
+			r.SetAbove(stats.TimeAbove(times, column.Values, value))
+		}
+	}
+	c.describe(r, files, column.Missing, key, phase)
+	return nil
 }
 
-// WriteToDisk will write the results to disk as a CSV and a JSON file
+// seconds returns a time as seconds since the epoch
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// describe sets the sources, the number of missing samples and the phase of a
+// result along with the unit and direction of its spec
+func (c *config) describe(r *result.ResultType, files []string, missing int, key, phase string) {
+	for _, file := range files {
+		r.Sources = append(r.Sources, c.relativePath(file))
+	}
+	r.Missing = missing
+	r.Phase = phase
+	r.Unit = c.specs[key].Unit
+	r.Direction = c.specs[key].Direction
+}
+
+// WriteToDisk will write the results to disk as a CSV and a JSON file, and
+// the histograms as another CSV file
 func (c *config) WriteToDisk() error {
-	err := utils.WriteCSV(c.resultDir, c.keys, c.fileHeader, c.hosts)
+	err := utils.WriteCSV(c.resultDir, c.hosts)
 	if err != nil {
 		return err
 	}
 
-	err = utils.WriteJSON(c.resultDir, result.Result{Hosts: c.hosts, Metrics: c.Metrics})
+	err = utils.WriteJSON(c.resultDir, result.Result{Provenance: &c.provenance, Run: c.run, Tags: c.tags, Hosts: c.hosts, Metrics: c.Metrics})
 	if err != nil {
 		return err
 	}
+
+	if c.histogram != stats.HistogramNone {
+		return utils.WriteHistograms(c.resultDir, c.hosts)
+	}
 	return nil
 }
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openshift-scale/perf-analyzer/pkg/result"
+)
+
+func TestMetricPhases(t *testing.T) {
+	metrics := []result.Metric{
+		{Name: "nginx", Type: "metrics.TestDuration", Values: map[string]interface{}{"startTime": "2019-10-01T10:00:00Z", "testDurationSeconds": 90.5}},
+		{Name: "pods", Type: "metrics.PodStats", Values: map[string]interface{}{"startTime": "2019-10-01T10:00:00Z", "testDurationSeconds": 10.0}},
+		{Name: "broken", Type: "metrics.TestDuration", Values: map[string]interface{}{"startTime": "soon", "testDurationSeconds": 10.0}},
+	}
+	phases := metricPhases(metrics)
+	if len(phases) != 1 || phases[0].Name != "nginx" {
+		t.Fatalf("Expected a single nginx phase, got %+v", phases)
+	}
+	start := time.Date(2019, 10, 1, 10, 0, 0, 0, time.UTC)
+	if !phases[0].Start.Equal(start) || !phases[0].End.Equal(start.Add(90500*time.Millisecond)) {
+		t.Errorf("Expected a phase from %v lasting 90.5s, got %v to %v", start, phases[0].Start, phases[0].End)
+	}
+}
+
+func TestSteadySelectors(t *testing.T) {
+	steady, err := parseSteady("cpu_*/etcd,memory_used,disk_*/?da-*")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	c := &config{steady: steady}
+	tests := []struct {
+		key, kind        string
+		resource, result bool
+	}{
+		{"cpu_usage_percent_cpu", "etcd", true, true},
+		{"cpu_usage_percent_cpu", "kubelet", true, false},
+		{"memory_used", "anything", true, true},
+		{"memory_free", "anything", false, false},
+		{"disk_utilization", "vda-write", true, true},
+		{"disk_utilization", "nvme0n1-write", true, false},
+	}
+	for _, test := range tests {
+		if got := c.steadyResource(test.key); got != test.resource {
+			t.Errorf("steadyResource(%q) = %t, expected %t", test.key, got, test.resource)
+		}
+		if got := c.isSteady(test.key, test.kind); got != test.result {
+			t.Errorf("isSteady(%q, %q) = %t, expected %t", test.key, test.kind, got, test.result)
+		}
+	}
+
+	c.steady, _ = parseSteady("cpu_usage_percent_cpu/etcd")
+	if !c.steadyResource("cpu_usage_percent_cpu") || !c.isSteady("cpu_usage_percent_cpu", "etcd") || c.isSteady("cpu_usage_percent_cpu", "kubelet") {
+		t.Errorf("Expected only the etcd result of cpu_usage_percent_cpu to be steady")
+	}
+}
+
+func TestParsePercentiles(t *testing.T) {
+	percentiles, err := ParsePercentiles("50, 90,95,99.9,")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(percentiles) != 2 || percentiles[0] != 90 || percentiles[1] != 99.9 {
+		t.Errorf("Expected the median and p95 to be skipped, got %v", percentiles)
+	}
+	if _, err := ParsePercentiles("101"); err == nil {
+		t.Errorf("Expected an error for a percentile above 100")
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/openshift-scale/perf-analyzer/pkg/result"
+)
+
+// Column sources name the command line flag that supplies the column selectors of a ToolSpec
+const (
+	SourceBlock = "blkdev"
+	// SourceBlockDevice is the blkdev list without the -read/-write suffixes, for
+	// iostat files that have a single column per device
+	SourceBlockDevice = "blkdev-device"
+	SourceNet         = "netdev"
+	SourceProcess     = "proc"
+)
+
+// ToolSpec describes how results are extracted from a single pbench tool CSV file
+type ToolSpec struct {
+	// File is the CSV file name searched for in each host directory
+	File string
+	// Resource names the results, defaults to File without the .csv extension
+	Resource string `json:",omitempty"`
+	// Source is the flag supplying the columns to extract (blkdev, netdev or proc)
+	Source string `json:",omitempty"`
+	// Columns is a fixed list of column selectors, used when Source is empty
+	Columns []string `json:",omitempty"`
+	// Match and Aggregate override the command line defaults for this file
+	Match     string `json:",omitempty"`
+	Aggregate string `json:",omitempty"`
+	// Files combines the columns of every file matching File (sum, max or
+	// separate), ie. one mpstat CSV per core. Separate results are prefixed with
+	// the first capture group of File, or the file name.
+	Files string `json:",omitempty"`
+	// Unit of the extracted values, ie. "%" or "IOPS"
+	Unit string `json:",omitempty"`
+	// Direction tells compare whether a lower or a higher value is better
+	Direction string `json:",omitempty"`
+}
+
+// DefaultSpecs are the pbench tool CSV files extracted when no spec file overrides them
+var DefaultSpecs = []ToolSpec{
+	{File: "disk_IOPS.csv", Source: SourceBlock, Unit: "IOPS"},
+	{File: "network_l2_network_packets_sec.csv", Source: SourceNet, Unit: "packets/s"},
+	{File: "network_l2_network_Mbits_sec.csv", Source: SourceNet, Unit: "Mbit/s"},
+	{File: "network_l2_network_errors_sec.csv", Source: SourceNet, Unit: "errors/s", Direction: result.DirectionLower},
+	{File: "network_l2_network_drops_sec.csv", Source: SourceNet, Unit: "drops/s", Direction: result.DirectionLower},
+	{File: "network_l4_tcp_errors_sec.csv", Resource: "network_tcp_errors", Columns: []string{"retrans=retrans(/s)?", "atmptf=atmptf(/s)?", "estres=estres(/s)?", "isegerr=isegerr(/s)?", "orsts=orsts(/s)?"}, Match: string(result.MatchAnchored), Unit: "segments/s", Direction: result.DirectionLower},
+	{File: "network_l3_sockets.csv", Resource: "network_sockets", Columns: []string{"totsck", "tcpsck", "udpsck", "tcp-tw"}, Match: string(result.MatchExact), Unit: "sockets"},
+	{File: "disk_Wait_Time_msec.csv", Source: SourceBlock, Unit: "ms", Direction: result.DirectionLower},
+	{File: "disk_Request_Size_in_512_byte_sectors.csv", Source: SourceBlock, Unit: "sectors"},
+	{File: "disk_Throughput_MB_per_sec.csv", Source: SourceBlock, Unit: "MB/s"},
+	{File: "disk_Utilization_percent.csv", Source: SourceBlockDevice, Unit: "%", Direction: result.DirectionLower},
+	{File: "disk_Queue_Size.csv", Source: SourceBlockDevice, Unit: "requests", Direction: result.DirectionLower},
+	{File: "cpuall_cpuall.csv", Resource: "cpu_all", Columns: []string{"usr=%?usr", "sys=%?sys", "iowait=%?iowait", "steal=%?steal", "soft=%?soft"}, Match: string(result.MatchAnchored), Unit: "%", Direction: result.DirectionLower},
+	{File: `(cpu\d+)_cpu\d+\.csv`, Resource: "cpu_core", Columns: []string{"busy=%?(usr|nice|sys|irq|soft|steal)"}, Match: string(result.MatchAnchored), Aggregate: string(result.AggregateSum), Files: string(result.AggregateSeparate), Unit: "%", Direction: result.DirectionLower},
+	{File: `(cpu\d+)_cpu\d+\.csv`, Resource: "cpu_hottest_core", Columns: []string{"busy=%?(usr|nice|sys|irq|soft|steal)", "soft=%?soft"}, Match: string(result.MatchAnchored), Aggregate: string(result.AggregateSum), Files: string(result.AggregateMax), Unit: "%", Direction: result.DirectionLower},
+	{File: "memory_memory_usage_KB.csv", Resource: "memory_host", Columns: []string{"used=kbmemused", "cached=kbcached", "committed=kbcommit", "slab=kbslab"}, Match: string(result.MatchExact), Unit: "KB", Direction: result.DirectionLower},
+	{File: "memory_memory_usage_KB.csv", Resource: "memory_host_available", Columns: []string{"available=kbavail"}, Match: string(result.MatchExact), Unit: "KB", Direction: result.DirectionHigher},
+	{File: "memory_swap_usage_KB.csv", Resource: "memory_swap", Columns: []string{"used=kbswpused", "cached=kbswpcad"}, Match: string(result.MatchExact), Unit: "KB", Direction: result.DirectionLower},
+	{File: "memory_paging_sec.csv", Resource: "memory_paging", Columns: []string{"pgpgin=pgpgin(/s)?", "pgpgout=pgpgout(/s)?", "faults=fault(/s)?", "majfaults=majflt(/s)?"}, Match: string(result.MatchAnchored), Unit: "/s", Direction: result.DirectionLower},
+	{File: "cpu_usage_percent_cpu.csv", Source: SourceProcess, Unit: "%", Direction: result.DirectionLower},
+	{File: "memory_usage_resident_set_size.csv", Source: SourceProcess, Unit: "KB", Direction: result.DirectionLower},
+	{File: "memory_usage_virtual_size.csv", Source: SourceProcess, Unit: "KB", Direction: result.DirectionLower},
+	{File: "memory_faults_minor_faults_sec.csv", Source: SourceProcess, Unit: "faults/s", Direction: result.DirectionLower},
+	{File: "memory_faults_major_faults_sec.csv", Source: SourceProcess, Unit: "faults/s", Direction: result.DirectionLower},
+	{File: "context_switches_voluntary_switches_sec.csv", Source: SourceProcess, Unit: "switches/s", Direction: result.DirectionLower},
+	{File: "context_switches_nonvoluntary_switches_sec.csv", Source: SourceProcess, Unit: "switches/s", Direction: result.DirectionLower},
+	{File: "kernel_tables_threads.csv", Source: SourceProcess, Unit: "threads", Direction: result.DirectionLower},
+	{File: "kernel_tables_fd_nr.csv", Source: SourceProcess, Unit: "fds", Direction: result.DirectionLower},
+	{File: "io_reads_KB_sec.csv", Source: SourceProcess, Unit: "KB/s"},
+	{File: "io_writes_KB_sec.csv", Source: SourceProcess, Unit: "KB/s"},
+}
+
+// ReadSpecs will read a JSON list of ToolSpec from a file
+func ReadSpecs(file string) ([]ToolSpec, error) {
+	var specs []ToolSpec
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(raw, &specs)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid spec file %s: %v", file, err)
+	}
+	return specs, nil
+}
+
+// MergeSpecs returns the defaults with user specs appended, a user spec
+// replaces the default spec with the same resource name
+func MergeSpecs(defaults, user []ToolSpec) []ToolSpec {
+	var merged []ToolSpec
+	for _, d := range defaults {
+		replaced := false
+		for _, u := range user {
+			if u.resource() == d.resource() {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, d)
+		}
+	}
+	return append(merged, user...)
+}
+
+// resource returns the result resource name of a spec
+func (s ToolSpec) resource() string {
+	if s.Resource != "" {
+		return s.Resource
+	}
+	return strings.TrimSuffix(s.File, ".csv")
+}
+
+// selectors will build the column selectors of a spec from its source flag or fixed columns
+func (s ToolSpec) selectors(cfg ScrapeConfig) ([]result.Selector, error) {
+	if s.File == "" {
+		return nil, fmt.Errorf("Spec for %q has no file", s.Resource)
+	}
+	if _, err := regexp.Compile(s.File); err != nil {
+		return nil, fmt.Errorf("Invalid file pattern %q: %v", s.File, err)
+	}
+	switch result.Aggregation(s.Files) {
+	case "", result.AggregateSum, result.AggregateMax, result.AggregateSeparate:
+	default:
+		return nil, fmt.Errorf("Unknown file aggregation %q for %s", s.Files, s.File)
+	}
+	mode := result.MatchMode(cfg.MatchFlag)
+	if s.Match != "" {
+		mode = result.MatchMode(s.Match)
+	}
+	if mode == "" {
+		mode = s.defaultMatch()
+	}
+	agg := result.Aggregation(cfg.AggregateFlag)
+	if s.Aggregate != "" {
+		agg = result.Aggregation(s.Aggregate)
+	}
+
+	var list string
+	switch s.Source {
+	case SourceBlock:
+		list = cfg.BlockString
+	case SourceBlockDevice:
+		list = deviceNames(cfg.BlockString)
+	case SourceNet:
+		list = cfg.NetString
+	case SourceProcess:
+		list = cfg.ProcessString
+	case "":
+		list = strings.Join(s.Columns, ",")
+	default:
+		return nil, fmt.Errorf("Unknown source %q for %s", s.Source, s.File)
+	}
+	return result.ParseSelectors(list, mode, agg)
+}
+
+// defaultMatch returns the match mode of a spec when neither the spec nor the
+// command line sets one. Device names are whole headers, so vda does not pick
+// up xvda, while process names are found within the pid-name headers.
+func (s ToolSpec) defaultMatch() result.MatchMode {
+	switch s.Source {
+	case SourceBlock, SourceBlockDevice, SourceNet:
+		return result.MatchAnchored
+	}
+	return result.MatchRegex
+}
+
+// deviceNames strips the -read and -write suffixes from a list of block devices
+// and removes the resulting duplicates, keeping any aggregation suffix
+func deviceNames(list string) string {
+	var names []string
+	seen := map[string]bool{}
+	for _, item := range strings.Split(list, ",") {
+		pattern, agg := result.SplitAggregation(item)
+		pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "-read"), "-write")
+		if agg != "" {
+			pattern = pattern + ":" + string(agg)
+		}
+		if pattern != "" && !seen[pattern] {
+			seen[pattern] = true
+			names = append(names, pattern)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// fileLabel names the results of one of several files matched by a spec, using
+// the first capture group of the spec file pattern or the file name
+func (s ToolSpec) fileLabel(file string) string {
+	name := filepath.Base(file)
+	match := regexp.MustCompile(s.File).FindStringSubmatch(name)
+	if len(match) > 1 && match[1] != "" {
+		return match[1]
+	}
+	return strings.TrimSuffix(name, ".csv")
+}
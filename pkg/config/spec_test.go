@@ -0,0 +1,130 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/openshift-scale/perf-analyzer/pkg/result"
+)
+
+func TestDeviceSelectorsAnchored(t *testing.T) {
+	headers := []string{"timestamp_ms", "vda-read", "vda-write", "xvda-read", "xvda-write"}
+	spec := ToolSpec{File: "disk_IOPS.csv", Source: SourceBlock}
+	selectors, err := spec.selectors(ScrapeConfig{BlockString: "vda-write", AggregateFlag: "sum"})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(selectors) != 1 || selectors[0].Mode != result.MatchAnchored {
+		t.Fatalf("Expected an anchored device selector, got %+v", selectors)
+	}
+	if got := selectors[0].Columns(headers); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected vda-write to match column 2 only, got columns %v", got)
+	}
+
+	// Process names are found within the pid-name headers
+	spec = ToolSpec{File: "cpu_usage_percent_cpu.csv", Source: SourceProcess}
+	selectors, _ = spec.selectors(ScrapeConfig{ProcessString: "etcd", AggregateFlag: "sum"})
+	if len(selectors) != 1 || !selectors[0].Match("123-etcd") {
+		t.Errorf("Expected etcd to match 123-etcd, got %+v", selectors)
+	}
+
+	// The command line overrides the default
+	spec = ToolSpec{File: "disk_IOPS.csv", Source: SourceBlock}
+	selectors, _ = spec.selectors(ScrapeConfig{BlockString: "vda-write", MatchFlag: "regex", AggregateFlag: "sum"})
+	if len(selectors) != 1 || !selectors[0].Match("xvda-write") {
+		t.Errorf("Expected a regex selector to match xvda-write, got %+v", selectors)
+	}
+}
+
+func TestMergeSpecs(t *testing.T) {
+	defaults := []ToolSpec{
+		{File: "disk_IOPS.csv", Source: SourceBlock, Unit: "IOPS"},
+		{File: "cpuall_cpuall.csv", Resource: "cpu_all", Columns: []string{"usr"}},
+	}
+	user := []ToolSpec{
+		{File: "disk_IOPS.csv", Source: SourceBlockDevice, Unit: "ops"},
+		{File: "custom.csv", Columns: []string{"value"}},
+	}
+	merged := MergeSpecs(defaults, user)
+	want := []string{"cpu_all", "disk_IOPS", "custom"}
+	if len(merged) != len(want) {
+		t.Fatalf("Expected %v, got %+v", want, merged)
+	}
+	for i, resource := range want {
+		if merged[i].resource() != resource {
+			t.Errorf("Expected %v at %d, got %v", resource, i, merged[i].resource())
+		}
+	}
+	if merged[1].Unit != "ops" || merged[1].Source != SourceBlockDevice {
+		t.Errorf("Expected the user spec to replace the default, got %+v", merged[1])
+	}
+	if len(MergeSpecs(defaults, nil)) != len(defaults) {
+		t.Errorf("Expected the defaults without user specs")
+	}
+}
+
+var deviceNamesTests = []struct {
+	list, want string
+}{
+	{"sda-write,sda-read,vda-write", "sda,vda"},
+	{"sda-write:max,sda-read", "sda:max,sda"},
+	{"nvme0n1,,eth0-read", "nvme0n1,eth0"},
+	{"", ""},
+}
+
+func TestDeviceNames(t *testing.T) {
+	for _, v := range deviceNamesTests {
+		if got := deviceNames(v.list); got != v.want {
+			t.Errorf("For %q, expected %q instead we got %q", v.list, v.want, got)
+		}
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	cfg := ScrapeConfig{BlockString: "sda-write,sda-read", NetString: "eth0-rx", ProcessString: "etcd:max", AggregateFlag: "sum"}
+	var tests = []struct {
+		spec  ToolSpec
+		names []string
+	}{
+		{ToolSpec{File: "disk_IOPS.csv", Source: SourceBlock}, []string{"sda-write", "sda-read"}},
+		{ToolSpec{File: "disk_Queue_Size.csv", Source: SourceBlockDevice}, []string{"sda"}},
+		{ToolSpec{File: "network.csv", Source: SourceNet}, []string{"eth0-rx"}},
+		{ToolSpec{File: "cpu.csv", Source: SourceProcess}, []string{"etcd"}},
+		{ToolSpec{File: "cpuall.csv", Columns: []string{"usr=%?usr", "sys"}}, []string{"usr", "sys"}},
+	}
+	for _, v := range tests {
+		selectors, err := v.spec.selectors(cfg)
+		if err != nil {
+			t.Fatalf("For %+v, unexpected error %v", v.spec, err)
+		}
+		if len(selectors) != len(v.names) {
+			t.Fatalf("For %+v, expected %v, got %+v", v.spec, v.names, selectors)
+		}
+		for i, name := range v.names {
+			if selectors[i].Name != name {
+				t.Errorf("For %+v, expected %v at %d, got %v", v.spec, name, i, selectors[i].Name)
+			}
+		}
+	}
+
+	selectors, _ := ToolSpec{File: "cpu.csv", Source: SourceProcess, Aggregate: "separate"}.selectors(cfg)
+	if selectors[0].Aggregate != result.AggregateMax {
+		t.Errorf("Expected a name suffix to override the spec aggregation, got %v", selectors[0].Aggregate)
+	}
+	selectors, _ = ToolSpec{File: "cpu.csv", Columns: []string{"usr"}, Match: "exact", Aggregate: "max"}.selectors(cfg)
+	if selectors[0].Mode != result.MatchExact || selectors[0].Aggregate != result.AggregateMax {
+		t.Errorf("Expected the spec to override the command line, got %+v", selectors[0])
+	}
+
+	invalid := []ToolSpec{
+		{Source: SourceBlock},
+		{File: "(disk.csv", Source: SourceBlock},
+		{File: "disk.csv", Source: "iostat"},
+		{File: "disk.csv", Source: SourceBlock, Files: "avg"},
+		{File: "disk.csv", Source: SourceBlock, Match: "fuzzy"},
+	}
+	for _, spec := range invalid {
+		if _, err := spec.selectors(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", spec)
+		}
+	}
+}
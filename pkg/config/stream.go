@@ -0,0 +1,416 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"time"
+
+	"github.com/openshift-scale/perf-analyzer/pkg/result"
+	"github.com/openshift-scale/perf-analyzer/pkg/stats"
+	"github.com/openshift-scale/perf-analyzer/pkg/utils"
+)
+
+// streamColumn is a column computed row by row. Each part combines cells of a
+// single file with its selector aggregation and the parts are combined with
+// the file aggregation of the spec.
+type streamColumn struct {
+	name  string
+	parts []streamPart
+	files result.Aggregation
+	paths []string
+}
+
+// streamPart is a set of cells of one file
+type streamPart struct {
+	file    int
+	indexes []int
+	agg     result.Aggregation
+}
+
+// value computes the column from the current row of each file, a missing cell makes it NaN
+func (col streamColumn) value(rows [][]string) float64 {
+	var value float64
+	for k, part := range col.parts {
+		var partValue float64
+		for j, index := range part.indexes {
+			cell, _, _ := result.ParseSample(rows[part.file][index])
+			if j == 0 {
+				partValue = cell
+				continue
+			}
+			partValue = aggregate(part.agg, partValue, cell)
+		}
+		if k == 0 {
+			value = partValue
+			continue
+		}
+		value = aggregate(col.files, value, partValue)
+	}
+	return value
+}
+
+// aggregate adds or takes the maximum of two samples like result.CombineColumns
+func aggregate(agg result.Aggregation, a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+	if agg == result.AggregateMax {
+		return math.Max(a, b)
+	}
+	return a + b
+}
+
+// streamResult summarizes a column within the window of a phase
+type streamResult struct {
+	column   *streamColumn
+	stream   *stats.Stream
+	integral stats.Integral
+	sampler  *stats.Sampler
+	above    *stats.Threshold
+	// timed is false once a sample without a valid timestamp is seen
+	timed   bool
+	missing int
+}
+
+// add counts a sample according to the missing value policy, t is zero when
+// the sample has no timestamp
+func (r *streamResult) add(t time.Time, value float64, policy result.MissingPolicy) error {
+	if math.IsNaN(value) {
+		r.missing++
+		switch policy {
+		case result.MissingFail:
+			return fmt.Errorf("Column %q has missing samples", r.column.name)
+		case result.MissingZero:
+			value = 0
+		default:
+			return nil
+		}
+	}
+	r.stream.Add(value)
+	if t.IsZero() {
+		r.timed = false
+	}
+	if r.timed {
+		if err := r.integral.Add(seconds(t), value); err != nil {
+			log.Printf("No time-based statistics for %s of %v: %v\n", r.column.name, r.column.paths, err)
+			r.timed = false
+			return nil
+		}
+		r.sampler.Add(seconds(t), value)
+		if r.above != nil {
+			r.above.Add(seconds(t), value)
+		}
+	}
+	return nil
+}
+
+// streamHost reads the CSV files of a host row by row, keeping a sketch of every
+// column instead of its values, so the memory used does not grow with the length of the run
+func (c *config) streamHost(i int, host result.Host) error {
+	// A first pass finds the time span of the host
+	paths := map[string][]string{}
+	readable := map[string]bool{}
+	var first, last time.Time
+	for _, key := range c.keys {
+		for _, file := range utils.FindFile(host.ResultDir, c.specs[key].File) {
+			ok, seen := readable[file]
+			if !seen {
+				start, end, rows, err := streamSpan(file)
+				if err != nil {
+					fmt.Printf("Error reading %v: %v\n", file, err)
+				} else {
+					ok = true
+					c.addSourceFile(file, rows)
+					if !start.IsZero() {
+						first, last = widenSpan(first, last, start, end)
+					}
+				}
+				readable[file] = ok
+			}
+			if ok {
+				paths[key] = append(paths[key], file)
+			}
+		}
+	}
+
+	window := c.spanWindow(first, last)
+	if len(c.steady) > 0 {
+		series, err := c.streamSteadySeries(paths, window)
+		if err != nil {
+			return err
+		}
+		window = c.steadyWindow(i, series, window)
+	}
+	// Phases get their own results, limited to the host window as well
+	windows := []result.Phase{{Window: window}}
+	for _, phase := range c.phases {
+		windows = append(windows, result.Phase{Name: phase.Name, Window: window.Intersect(phase.Window)})
+	}
+
+	for _, key := range c.keys {
+		// Files combined by a spec are read side by side
+		groups := [][]string{paths[key]}
+		if c.specs[key].Files == "" {
+			groups = nil
+			for _, file := range paths[key] {
+				groups = append(groups, []string{file})
+			}
+		}
+
+		results := make([][]*streamResult, len(windows))
+		claims := result.Claims{}
+		for _, group := range groups {
+			if len(group) == 0 {
+				continue
+			}
+			groupResults, err := c.streamFiles(key, group, windows, claims)
+			if err != nil {
+				return err
+			}
+			for w := range groupResults {
+				results[w] = append(results[w], groupResults[w]...)
+			}
+		}
+		// Results are added in the same order as when the files are read at once
+		for w, phase := range windows {
+			for _, r := range results[w] {
+				c.addStream(i, r, key, phase.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// streamSteadySeries reads the columns of the steady state resources of a host
+// within its window, the steady state is detected on the samples kept for the trend
+func (c *config) streamSteadySeries(paths map[string][]string, window result.Window) ([]steadySeries, error) {
+	var series []steadySeries
+	for _, key := range c.keys {
+		if !c.steadyResource(key) {
+			continue
+		}
+		for _, file := range paths[key] {
+			results, err := c.streamFiles(key, []string{file}, []result.Phase{{Window: window}}, nil)
+			if err != nil {
+				return nil, err
+			}
+			if len(results) == 0 {
+				continue
+			}
+			for _, r := range results[0] {
+				if !c.isSteady(key, r.column.name) || !r.timed {
+					continue
+				}
+				times, values := r.sampler.Samples()
+				series = append(series, steadySeries{name: key + " " + r.column.name, times: times, values: values})
+			}
+		}
+	}
+	return series, nil
+}
+
+// streamSpan reads the first and last timestamp and the number of rows of a CSV,
+// a CSV without valid timestamps has no span. Rows out of time order cannot be
+// streamed, they are only sorted when the CSV is read at once.
+func streamSpan(file string) (first, last time.Time, rows int, err error) {
+	r, err := utils.OpenCSV(file)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	header, err := r.Read()
+	if err != nil {
+		return
+	}
+	column, spanErr := result.TimestampColumn(header)
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return time.Time{}, time.Time{}, rows, err
+		}
+		rows++
+		if spanErr != nil {
+			continue
+		}
+		var t time.Time
+		t, spanErr = result.ParseTimestamp(row[column])
+		if spanErr != nil {
+			continue
+		}
+		if t.Before(last) {
+			return time.Time{}, time.Time{}, rows, fmt.Errorf("Row %d at %v is before the previous row at %v, sort the CSV by time to stream it", rows, t.UTC(), last.UTC())
+		}
+		first, last = widenSpan(first, last, t, t)
+	}
+	if spanErr != nil {
+		return time.Time{}, time.Time{}, rows, nil
+	}
+	return first, last, rows, nil
+}
+
+// streamFiles reads a group of files side by side, one row of each at a time,
+// and summarizes the columns of a resource within each window. The matched
+// headers are checked against claims unless it is nil.
+func (c *config) streamFiles(key string, files []string, windows []result.Phase, claims result.Claims) ([][]*streamResult, error) {
+	readers := make([]*utils.CSVReader, len(files))
+	headers := make([][]string, len(files))
+	for f, file := range files {
+		r, err := utils.OpenCSV(file)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		headers[f], err = r.Read()
+		if err != nil {
+			fmt.Printf("No header row in %v: %v\n", file, err)
+			return nil, nil
+		}
+		readers[f] = r
+	}
+
+	columns := c.streamColumns(key, files, headers, claims)
+	results := make([][]*streamResult, len(windows))
+	for w := range windows {
+		for k := range columns {
+			stream, err := stats.NewStream(c.accuracy)
+			if err != nil {
+				return nil, err
+			}
+			r := &streamResult{column: &columns[k], stream: stream, sampler: stats.NewSampler(), timed: true}
+			if value, ok := c.threshold(key); ok {
+				r.above = &stats.Threshold{Value: value}
+			}
+			results[w] = append(results[w], r)
+		}
+	}
+
+	timestamp, err := result.TimestampColumn(headers[0])
+	if err != nil {
+		fmt.Printf("Not limiting %v to the time window: %v\n", files[0], err)
+	}
+	rows := make([][]string, len(files))
+	values := make([]float64, len(columns))
+	for {
+		// Rows missing from shorter files are dropped
+		for f, r := range readers {
+			rows[f], err = r.Read()
+			if err == io.EOF {
+				return results, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", files[f], err)
+			}
+		}
+		for k := range columns {
+			values[k] = columns[k].value(rows)
+		}
+
+		// t stays zero for a row without a valid timestamp
+		var t time.Time
+		if timestamp >= 0 {
+			t, _ = result.ParseTimestamp(rows[0][timestamp])
+		}
+		timed := !t.IsZero()
+		for w, phase := range windows {
+			if timed && !phase.Contains(t) {
+				continue
+			}
+			for k, r := range results[w] {
+				err := r.add(t, values[k], c.missing)
+				if err != nil {
+					return nil, fmt.Errorf("%v: %v", files[0], err)
+				}
+			}
+		}
+	}
+}
+
+// streamColumns builds the columns of a resource from the headers of a group of
+// files, the matched headers are checked against claims unless it is nil
+func (c *config) streamColumns(key string, files []string, headers [][]string, claims result.Claims) []streamColumn {
+	spec := c.specs[key]
+	var columns []streamColumn
+	for _, header := range c.fileHeader[key] {
+		var fileColumns []streamColumn
+		for f := range files {
+			if claims != nil {
+				claims.Check(files[f], headers[f], header)
+			}
+			indexes := header.Columns(headers[f])
+			if len(indexes) == 0 {
+				fmt.Printf("No matching headers for pattern %q in %v\n", header.Pattern, files[f])
+				continue
+			}
+			prefix := ""
+			if spec.Files != "" {
+				prefix = spec.fileLabel(files[f]) + "-"
+			}
+			if header.Aggregate == result.AggregateSeparate {
+				for _, index := range indexes {
+					fileColumns = append(fileColumns, streamColumn{
+						name:  prefix + headers[f][index],
+						parts: []streamPart{{file: f, indexes: []int{index}}},
+						paths: files[f : f+1],
+					})
+				}
+				continue
+			}
+			fileColumns = append(fileColumns, streamColumn{
+				name:  prefix + header.Name,
+				parts: []streamPart{{file: f, indexes: indexes, agg: header.Aggregate}},
+				paths: files[f : f+1],
+			})
+		}
+
+		if spec.Files == "" || result.Aggregation(spec.Files) == result.AggregateSeparate || len(fileColumns) == 0 {
+			columns = append(columns, fileColumns...)
+			continue
+		}
+		combined := streamColumn{name: header.Name, files: result.Aggregation(spec.Files)}
+		for _, column := range fileColumns {
+			combined.parts = append(combined.parts, column.parts...)
+			combined.paths = append(combined.paths, column.paths...)
+		}
+		columns = append(columns, combined)
+	}
+	return columns
+}
+
+// addStream will add the statistics of a streamed column to a host
+func (c *config) addStream(i int, r *streamResult, key, phase string) {
+	summary, err := r.stream.Summarize(c.summary)
+	if err != nil {
+		log.Printf("No statistics for %s of %s on %s: %v", r.column.name, key, c.hosts[i].Kind, err)
+	}
+	if r.timed {
+		summary.SetIntegral(r.integral)
+		if trend, err := r.sampler.Trend(); err == nil {
+			summary.SetTrend(trend)
+		}
+	}
+	results := c.hosts[i].AddSummary(summary, r.column.paths[0], r.column.name, key)
+	res := &results[len(results)-1]
+	if c.sketch {
+		res.Sketch = r.stream.Sketch()
+	}
+	if c.histogram != stats.HistogramNone {
+		// Values are counted at the estimate of their sketch bucket
+		res.Histogram, _ = r.stream.Sketch().Histogram(c.histogram, c.buckets)
+	}
+	if r.timed {
+		// Spikes and change points are found among the samples kept for the trend
+		times, values := r.sampler.Samples()
+		c.addOutliers(res, times, values)
+		c.addChangePoints(res, times, values)
+		if r.above != nil {
+			res.SetAbove(*r.above)
+		}
+	}
+	c.describe(res, r.column.paths, r.missing, key, phase)
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openshift-scale/perf-analyzer/pkg/result"
+)
+
+// writeHost writes the pbench CSVs of a host with rows samples every 10s and a
+// timeline of a phase from 1000s to 3000s, the kubelet misses a sample and each
+// core gets a load of its own
+func writeHost(t *testing.T, dir string, rows int) {
+	err := ioutil.WriteFile(filepath.Join(dir, "timeline.csv"), []byte("load,1570001000000,1570003000000\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := filepath.Join(dir, "svt-master-1:pbench-001")
+	if err := os.MkdirAll(host, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*strings.Builder{}
+	add := func(name, format string, args ...interface{}) {
+		if files[name] == nil {
+			files[name] = &strings.Builder{}
+		}
+		fmt.Fprintf(files[name], format, args...)
+	}
+	add("cpu_usage_percent_cpu.csv", "timestamp_ms,123-etcd,456-hyperkube_kubelet_\n")
+	add("cpuall_cpuall.csv", "timestamp_ms,%%usr,%%sys\n")
+	add("cpu0_cpu0.csv", "timestamp_ms,%%usr,%%sys\n")
+	add("cpu1_cpu1.csv", "timestamp_ms,%%usr,%%sys\n")
+	for i := 0; i < rows; i++ {
+		ms := 1570000000000 + int64(i)*10000
+		kubelet := fmt.Sprintf("%.2f", 5+float64(i%7))
+		if i == rows/2 {
+			kubelet = ""
+		}
+		add("cpu_usage_percent_cpu.csv", "%d,%.2f,%s\n", ms, 20+10*math.Sin(float64(i)/10)+float64(i%13), kubelet)
+		add("cpuall_cpuall.csv", "%d,%.2f,%.2f\n", ms, 30+float64(i%17), 10+float64(i%5))
+		add("cpu0_cpu0.csv", "%d,%.2f,%.2f\n", ms, 40+float64(i%11), 5+float64(i%3))
+		add("cpu1_cpu1.csv", "%d,%.2f,%.2f\n", ms, 60-float64(i%19), 2+float64(i%4))
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(host, name), []byte(content.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// process extracts the results of the hosts under dir like the scraper
+func process(t *testing.T, dir string, stream bool) []result.Host {
+	c, err := NewConfig(ScrapeConfig{
+		TimelineFile:         filepath.Join(dir, "timeline.csv"),
+		EnablePbenchFlag:     true,
+		SearchDir:            dir,
+		ResultDir:            dir,
+		ResourceString:       "cpu_*",
+		ProcessString:        "etcd,kubelet",
+		AggregateFlag:        "sum",
+		MissingFlag:          "skip",
+		OutliersFlag:         "iqr",
+		PercentilesFlag:      "90,99",
+		PercentileMethodFlag: "linear",
+		HistogramFlag:        "none",
+		SketchAccuracyFlag:   0.01,
+		StreamFlag:           stream,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	c.Init()
+	if err := c.Process(); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	return c.hosts
+}
+
+// near reports whether two statistics are within a relative error of each other
+func near(a, b, relative float64) bool {
+	return a == b || math.Abs(a-b) <= relative*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func TestStreamMatchesBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeHost(t, dir, 500)
+
+	batch, stream := process(t, dir, false), process(t, dir, true)
+	if len(batch) != 1 || len(stream) != 1 {
+		t.Fatalf("Expected a single host, got %d and %d", len(batch), len(stream))
+	}
+	want, got := batch[0].Results, stream[0].Results
+	if len(want) == 0 || len(want) != len(got) {
+		t.Fatalf("Expected %d results when streaming, got %d", len(want), len(got))
+	}
+	resources := map[string]bool{}
+	missing := 0
+	for _, r := range want {
+		resources[r.Resource+" "+r.Phase] = true
+		missing += r.Missing
+	}
+	if len(resources) != 8 || missing != 2 {
+		t.Errorf("Expected 4 resources in 2 phases with 2 missing samples, got %v with %d", resources, missing)
+	}
+	for j := range want {
+		w, g := want[j], got[j]
+		if w.Key() != g.Key() {
+			t.Errorf("Expected result %d to be %+v, got %+v", j, w.Key(), g.Key())
+			continue
+		}
+		if w.Samples != g.Samples || w.Missing != g.Missing {
+			t.Errorf("%+v: expected %d samples and %d missing, got %d and %d", w.Key(), w.Samples, w.Missing, g.Samples, g.Missing)
+		}
+		if !near(w.Avg, g.Avg, 1e-12) || w.Min != g.Min || w.Max != g.Max {
+			t.Errorf("%+v: expected a mean of %v from %v to %v, got %v from %v to %v", w.Key(), w.Avg, w.Min, w.Max, g.Avg, g.Min, g.Max)
+		}
+		if !near(w.TimeWeightedAvg, g.TimeWeightedAvg, 1e-12) || !near(w.Integral, g.Integral, 1e-12) {
+			t.Errorf("%+v: expected a time-weighted mean of %v, got %v", w.Key(), w.TimeWeightedAvg, g.TimeWeightedAvg)
+		}
+		// Percentiles are estimated within the accuracy of the sketch
+		if !near(w.Median, g.Median, 0.01) || !near(w.Pct95, g.Pct95, 0.01) {
+			t.Errorf("%+v: expected a median of %v and p95 of %v, got %v and %v", w.Key(), w.Median, w.Pct95, g.Median, g.Pct95)
+		}
+		for name, value := range w.Percentiles {
+			if !near(value, g.Percentiles[name], 0.01) {
+				t.Errorf("%+v: expected %s of %v, got %v", w.Key(), name, value, g.Percentiles[name])
+			}
+		}
+	}
+}
+
+func TestStreamUnordered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeHost(t, dir, 20)
+	file := filepath.Join(dir, "svt-master-1:pbench-001", "cpu_usage_percent_cpu.csv")
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(content), "\n")
+	lines[5], lines[6] = lines[6], lines[5]
+	if err := ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the CSV at once sorts its rows, streaming it is refused
+	count := func(hosts []result.Host) (n int) {
+		for _, r := range hosts[0].Results {
+			if r.Resource == "cpu_usage_percent_cpu" {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(process(t, dir, false)); n != 4 {
+		t.Errorf("Expected 4 results of the unordered CSV, got %d", n)
+	}
+	if n := count(process(t, dir, true)); n != 0 {
+		t.Errorf("Expected the unordered CSV not to be streamed, got %d results", n)
+	}
+}
@@ -39,6 +39,8 @@ type TimeSeries struct {
 type rawData struct {
 	Name   string
 	Values []float64
+	// Times of the values in seconds since the epoch
+	Times []float64
 }
 
 type prometheusConfig struct {
@@ -90,6 +92,11 @@ func (c *prometheusConfig) newPrometheusAPI() {
 
 // DoPrometheusQuery will run queries against Prometheus endpoint
 func DoPrometheusQuery(cfg config.ScrapeConfig) {
+	opts, err := config.SummaryOptions(cfg)
+	if err != nil {
+		fmt.Printf("Unable to parse percentile options: %v\n", err)
+		return
+	}
 	config, err := newPrometheusConfig(cfg.UrlFlag, cfg.TokenFlag, cfg.InsecureTLSFlag)
 	if err != nil {
 		fmt.Printf("Unable to create Prometheus config: %v\n", err)
@@ -138,14 +145,19 @@ func DoPrometheusQuery(cfg config.ScrapeConfig) {
 			}
 			for _, v := range j.Values {
 				newData.Values = append(newData.Values, float64(v.Value))
+				newData.Times = append(newData.Times, float64(v.Timestamp)/1000)
 			}
 			series.Tags = append(series.Tags, newData)
 		}
 		fmt.Fprintf(os.Stderr, "Series: %+v\n", series)
 
 		for _, r := range series.Tags {
-			results = append(results, AddResult(r.Values, r.Name))
-			results[len(results)-1].Resource = resource
+			res, err := AddResult(r.Values, r.Times, r.Name, opts)
+			if err != nil {
+				fmt.Printf("No statistics for %s of %s: %v\n", r.Name, resource, err)
+			}
+			res.Resource = resource
+			results = append(results, res)
 		}
 	}
 
@@ -153,20 +165,22 @@ func DoPrometheusQuery(cfg config.ScrapeConfig) {
 
 }
 
-func AddResult(newResult []float64, kind string) result.ResultType {
-	min, _ := stats.Minimum(newResult)
-	max, _ := stats.Maximum(newResult)
-	avg, _ := stats.Mean(newResult)
-	pct95, _ := stats.Percentile(newResult, 95)
+// AddResult will summarize the values of a series sampled at times in seconds
+// with the statistics selected by opts
+func AddResult(newResult, times []float64, kind string, opts stats.Options) (result.ResultType, error) {
+	summary, err := stats.Summarize(newResult, opts)
+	if integral, err := stats.Integrate(times, newResult); err == nil {
+		summary.SetIntegral(integral)
+	}
+	if trend, err := stats.TheilSen(times, newResult); err == nil {
+		summary.SetTrend(trend)
+	}
 
 	result := result.ResultType{
-		Kind:  kind,
-		Min:   min,
-		Max:   max,
-		Avg:   avg,
-		Pct95: pct95,
+		Kind: kind,
 	}
+	result.SetSummary(summary)
 
-	return result
+	return result, err
 
 }
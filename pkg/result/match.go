@@ -0,0 +1,324 @@
+package result
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MatchMode controls how a selector pattern is compared against CSV headers
+type MatchMode string
+
+const (
+	// MatchRegex matches when the pattern is found anywhere in the header
+	MatchRegex MatchMode = "regex"
+	// MatchAnchored matches when the pattern regexp matches the whole header
+	MatchAnchored MatchMode = "anchored"
+	// MatchExact matches when the header is identical to the pattern
+	MatchExact MatchMode = "exact"
+	// MatchGlob matches the whole header against a shell style glob (*, ?)
+	MatchGlob MatchMode = "glob"
+)
+
+// Aggregation controls how several columns matched by one selector are combined
+type Aggregation string
+
+const (
+	// AggregateSum adds the matched columns together row by row
+	AggregateSum Aggregation = "sum"
+	// AggregateMax keeps the highest of the matched columns row by row
+	AggregateMax Aggregation = "max"
+	// AggregateSeparate keeps every matched column as its own result
+	AggregateSeparate Aggregation = "separate"
+)
+
+// timestampHeader is the name of the first column of every pbench CSV
+const timestampHeader = "timestamp_ms"
+
+// Selector picks one or more columns out of a CSV file, Name is used for
+// the aggregated column and defaults to the pattern
+type Selector struct {
+	Name      string
+	Pattern   string
+	Mode      MatchMode
+	Aggregate Aggregation
+	regex     *regexp.Regexp
+}
+
+// Column is a named series of values extracted from a CSV, Sources lists
+// the headers the values were taken from. Missing samples are NaN until
+// ApplyMissing counts them into Missing.
+type Column struct {
+	Name       string
+	Sources    []string
+	Timestamps []time.Time
+	Values     []float64
+	Missing    int
+}
+
+// NewSelector validates the mode and aggregation and compiles the pattern
+func NewSelector(pattern string, mode MatchMode, agg Aggregation) (Selector, error) {
+	s := Selector{Name: pattern, Pattern: pattern, Mode: mode, Aggregate: agg}
+	switch agg {
+	case AggregateSum, AggregateMax, AggregateSeparate:
+	default:
+		return s, fmt.Errorf("Unknown aggregation %q for pattern %q", agg, pattern)
+	}
+
+	var expr string
+	switch mode {
+	case MatchRegex:
+		expr = pattern
+	case MatchAnchored:
+		expr = "^(?:" + pattern + ")$"
+	case MatchExact:
+		expr = "^" + regexp.QuoteMeta(pattern) + "$"
+	case MatchGlob:
+		expr = globToRegex(pattern)
+	default:
+		return s, fmt.Errorf("Unknown match mode %q for pattern %q", mode, pattern)
+	}
+
+	var err error
+	s.regex, err = regexp.Compile(expr)
+	if err != nil {
+		return s, fmt.Errorf("Invalid pattern %q: %v", pattern, err)
+	}
+	return s, nil
+}
+
+// ParseSelectors splits a comma-separated list of patterns into selectors.
+// Each pattern may override the default aggregation with a suffix, ie. etcd:max,
+// and may be given a name with a prefix, ie. busy=usr|sys. A comma or an equals
+// sign escaped with a backslash is part of the pattern, ie. x{1\,3}.
+func ParseSelectors(list string, mode MatchMode, agg Aggregation) ([]Selector, error) {
+	var selectors []Selector
+	for _, item := range splitEscaped(list, ',', -1) {
+		if item == "" {
+			continue
+		}
+		pattern, itemAgg := SplitAggregation(item)
+		if itemAgg == "" {
+			itemAgg = agg
+		}
+		name := ""
+		if parts := splitEscaped(pattern, '=', 2); len(parts) == 2 {
+			name, pattern = parts[0], parts[1]
+		} else {
+			pattern = parts[0]
+		}
+		s, err := NewSelector(pattern, mode, itemAgg)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			s.Name = name
+		}
+		selectors = append(selectors, s)
+	}
+	return selectors, nil
+}
+
+// splitEscaped splits s into at most n parts, or all parts when n is negative,
+// at every sep not escaped with a backslash. The backslash of an escaped sep is removed.
+func splitEscaped(s string, sep byte, n int) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == sep:
+			b.WriteByte(sep)
+			i++
+		case s[i] == sep && (n < 0 || len(parts) < n-1):
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(parts, b.String())
+}
+
+// SplitAggregation separates a pattern from its aggregation suffix, ie. etcd:max,
+// the returned aggregation is empty when the item has no valid suffix
+func SplitAggregation(item string) (string, Aggregation) {
+	if i := strings.LastIndex(item, ":"); i != -1 {
+		switch suffix := Aggregation(item[i+1:]); suffix {
+		case AggregateSum, AggregateMax, AggregateSeparate:
+			return item[:i], suffix
+		}
+	}
+	return item, ""
+}
+
+// Match reports whether a header is selected
+func (s Selector) Match(header string) bool {
+	if s.regex == nil {
+		return false
+	}
+	return s.regex.MatchString(header)
+}
+
+// MatchesAny reports whether a header is selected by any of the selectors
+func MatchesAny(selectors []Selector, header string) bool {
+	for _, s := range selectors {
+		if s.Match(header) {
+			return true
+		}
+	}
+	return false
+}
+
+// Columns returns the index of every header matched by the selector,
+// the timestamp column is never matched
+func (s Selector) Columns(headers []string) []int {
+	var columns []int
+	for i, h := range headers {
+		if h == timestampHeader {
+			continue
+		}
+		if s.Match(h) {
+			columns = append(columns, i)
+		}
+	}
+	return columns
+}
+
+// Claims records the pattern that first matched each header of each file, to
+// report headers matched by several patterns
+type Claims map[string]map[string]string
+
+// Check reports the headers of a file matched by a selector that are duplicated,
+// that make an unnamed pattern ambiguous or that an earlier pattern already claimed
+func (c Claims) Check(file string, headers []string, s Selector) {
+	indexes := s.Columns(headers)
+	var sources []string
+	seen := map[string]int{}
+	for _, i := range indexes {
+		if j, ok := seen[headers[i]]; ok {
+			log.Printf("Duplicate header %q in columns %d and %d of %v for pattern %q\n", headers[i], j, i, file, s.Pattern)
+		}
+		seen[headers[i]] = i
+		sources = append(sources, headers[i])
+	}
+	// A named selector combines several columns on purpose
+	if len(indexes) > 1 && s.Name == s.Pattern {
+		log.Printf("Pattern %q is ambiguous in %v, matched %d columns %q, using %s\n", s.Pattern, file, len(indexes), sources, s.Aggregate)
+	}
+
+	if c[file] == nil {
+		c[file] = map[string]string{}
+	}
+	for _, i := range indexes {
+		if seen[headers[i]] != i {
+			continue
+		}
+		if pattern, ok := c[file][headers[i]]; ok {
+			log.Printf("Header %q in %v matched by both %q and %q\n", headers[i], file, pattern, s.Pattern)
+			continue
+		}
+		c[file][headers[i]] = s.Pattern
+	}
+}
+
+// NewColumns extracts the columns matched by a selector from a CSV and
+// combines them according to the selector aggregation
+func NewColumns(bigSlice [][]string, s Selector) ([]Column, error) {
+	if len(bigSlice) == 0 {
+		return nil, fmt.Errorf("No header row for pattern %q", s.Pattern)
+	}
+	headers := bigSlice[0]
+	indexes := s.Columns(headers)
+	if len(indexes) == 0 {
+		err := fmt.Errorf("No matching headers for pattern %q", s.Pattern)
+		log.Println(err)
+		return nil, err
+	}
+
+	var sources []string
+	for _, i := range indexes {
+		sources = append(sources, headers[i])
+	}
+
+	// A CSV without timestamps still has values
+	timestamps, _ := Timestamps(bigSlice)
+	var columns []Column
+	for k, i := range indexes {
+		values, empty, invalid := columnValues(bigSlice, i)
+		if empty > 0 || invalid > 0 {
+			log.Printf("Column %q has %d empty and %d non-numeric samples out of %d\n", sources[k], empty, invalid, len(values))
+		}
+		columns = append(columns, Column{
+			Name:       sources[k],
+			Sources:    []string{sources[k]},
+			Timestamps: timestamps,
+			Values:     values,
+		})
+	}
+	if s.Aggregate == AggregateSeparate {
+		return columns, nil
+	}
+	return []Column{CombineColumns(columns, s.Name, s.Aggregate)}, nil
+}
+
+// CombineColumns adds or takes the maximum of columns row by row into a single
+// named column, rows missing from shorter columns are dropped and a sample
+// missing from any column is missing from the combined column
+func CombineColumns(columns []Column, name string, agg Aggregation) Column {
+	combined := Column{Name: name}
+	if len(columns) == 0 {
+		return combined
+	}
+	rows := len(columns[0].Values)
+	for _, column := range columns {
+		if len(column.Values) < rows {
+			rows = len(column.Values)
+		}
+	}
+
+	combined.Values = make([]float64, rows)
+	copy(combined.Values, columns[0].Values)
+	if len(columns[0].Timestamps) >= rows {
+		combined.Timestamps = columns[0].Timestamps[:rows]
+	}
+	combined.Sources = append(combined.Sources, columns[0].Sources...)
+	for _, column := range columns[1:] {
+		combined.Sources = append(combined.Sources, column.Sources...)
+		for row := range combined.Values {
+			if math.IsNaN(column.Values[row]) {
+				combined.Values[row] = math.NaN()
+				continue
+			}
+			switch agg {
+			case AggregateSum:
+				combined.Values[row] += column.Values[row]
+			case AggregateMax:
+				if column.Values[row] > combined.Values[row] {
+					combined.Values[row] = column.Values[row]
+				}
+			}
+		}
+	}
+	return combined
+}
+
+// globToRegex converts a shell style glob into an anchored regexp
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
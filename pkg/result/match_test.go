@@ -0,0 +1,186 @@
+package result
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"os"
+	"testing"
+)
+
+var matchTests = []struct {
+	pattern string
+	mode    MatchMode
+	header  string
+	want    bool
+}{
+	{"vda-write", MatchRegex, "vda-write", true},
+	{"vda-write", MatchRegex, "xvda-write", true},
+	{"vda-write", MatchAnchored, "xvda-write", false},
+	{"vda-write", MatchAnchored, "vda-write", true},
+	{"x?vda-write", MatchAnchored, "xvda-write", true},
+	{"vda-write", MatchExact, "xvda-write", false},
+	{"vda.write", MatchExact, "vda-write", false},
+	{"vda.write", MatchAnchored, "vda-write", true},
+	{"*vda-*", MatchGlob, "xvda-write", true},
+	{"vda-*", MatchGlob, "xvda-write", false},
+	{"vda-?", MatchGlob, "vda-write", false},
+	{"etcd", MatchRegex, "123-etcd", true},
+	{"etcd", MatchAnchored, "123-etcd", false},
+}
+
+func TestSelectorMatch(t *testing.T) {
+	for _, v := range matchTests {
+		s, err := NewSelector(v.pattern, v.mode, AggregateSum)
+		if err != nil {
+			t.Fatalf("For %q %s, unexpected error %v", v.pattern, v.mode, err)
+		}
+		if got := s.Match(v.header); got != v.want {
+			t.Errorf("For %q %s, expected a match of %q to be %v", v.pattern, v.mode, v.header, v.want)
+		}
+	}
+}
+
+func TestSelectorColumns(t *testing.T) {
+	headers := []string{"timestamp_ms", "vda-read", "vda-write", "xvda-read", "xvda-write"}
+	regex, _ := NewSelector("vda-write", MatchRegex, AggregateSum)
+	if got := regex.Columns(headers); len(got) != 2 {
+		t.Errorf("Expected vda-write to match xvda-write as a regex, got columns %v", got)
+	}
+	anchored, _ := NewSelector("vda-write", MatchAnchored, AggregateSum)
+	if got := anchored.Columns(headers); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected vda-write to match column 2 only when anchored, got columns %v", got)
+	}
+	all, _ := NewSelector(".*", MatchRegex, AggregateSum)
+	if got := all.Columns(headers); len(got) != 4 {
+		t.Errorf("Expected the timestamp column never to match, got columns %v", got)
+	}
+}
+
+func TestNewSelectorErrors(t *testing.T) {
+	if _, err := NewSelector("vda", "fuzzy", AggregateSum); err == nil {
+		t.Errorf("Expected an error for an unknown match mode")
+	}
+	if _, err := NewSelector("vda", MatchRegex, "avg"); err == nil {
+		t.Errorf("Expected an error for an unknown aggregation")
+	}
+	if _, err := NewSelector("vda(", MatchRegex, AggregateSum); err == nil {
+		t.Errorf("Expected an error for an invalid regex")
+	}
+	if _, err := NewSelector("vda(", MatchExact, AggregateSum); err != nil {
+		t.Errorf("Expected an exact pattern to be quoted, got %v", err)
+	}
+}
+
+func TestParseSelectors(t *testing.T) {
+	selectors, err := ParseSelectors("etcd:max,busy=usr|sys,,vda-write:separate,fluentd", MatchRegex, AggregateSum)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	want := []Selector{
+		{Name: "etcd", Pattern: "etcd", Aggregate: AggregateMax},
+		{Name: "busy", Pattern: "usr|sys", Aggregate: AggregateSum},
+		{Name: "vda-write", Pattern: "vda-write", Aggregate: AggregateSeparate},
+		{Name: "fluentd", Pattern: "fluentd", Aggregate: AggregateSum},
+	}
+	if len(selectors) != len(want) {
+		t.Fatalf("Expected %d selectors, got %+v", len(want), selectors)
+	}
+	for i, s := range selectors {
+		if s.Name != want[i].Name || s.Pattern != want[i].Pattern || s.Aggregate != want[i].Aggregate || s.Mode != MatchRegex {
+			t.Errorf("Expected %+v, got %+v", want[i], s)
+		}
+	}
+
+	// An unknown suffix is part of the pattern
+	selectors, _ = ParseSelectors("a:avg", MatchExact, AggregateSum)
+	if len(selectors) != 1 || selectors[0].Pattern != "a:avg" {
+		t.Errorf("Expected a:avg to be a pattern, got %+v", selectors)
+	}
+	if _, err := ParseSelectors("etcd", "fuzzy", AggregateSum); err == nil {
+		t.Errorf("Expected an error for an unknown match mode")
+	}
+
+	// Escaped commas and equals signs are part of the pattern
+	selectors, err = ParseSelectors(`x{1\,3}:max,a\=b,n=c\=d`, MatchAnchored, AggregateSum)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	want = []Selector{
+		{Name: "x{1,3}", Pattern: "x{1,3}", Aggregate: AggregateMax},
+		{Name: "a=b", Pattern: "a=b", Aggregate: AggregateSum},
+		{Name: "n", Pattern: "c=d", Aggregate: AggregateSum},
+	}
+	if len(selectors) != len(want) {
+		t.Fatalf("Expected %d selectors, got %+v", len(want), selectors)
+	}
+	for i, s := range selectors {
+		if s.Name != want[i].Name || s.Pattern != want[i].Pattern || s.Aggregate != want[i].Aggregate {
+			t.Errorf("Expected %+v, got %+v", want[i], s)
+		}
+	}
+	if !selectors[0].Match("xxx") || selectors[0].Match("xxxx") || !selectors[2].Match("c=d") {
+		t.Errorf("Expected the escaped patterns to be matched as written")
+	}
+
+	globs, _ := ParseSelectors("memory_*,cpu_all", MatchGlob, AggregateSum)
+	if !MatchesAny(globs, "memory_used") || !MatchesAny(globs, "cpu_all") || MatchesAny(globs, "cpu_all_busy") || MatchesAny(nil, "cpu_all") {
+		t.Errorf("Expected only memory_* and cpu_all to be matched by %+v", globs)
+	}
+}
+
+func TestClaimsCheck(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	headers := []string{"timestamp_ms", "1-etcd", "2-etcd", "1-etcd", "3-kubelet"}
+	etcd, _ := NewSelector("etcd", MatchRegex, AggregateSum)
+	second, _ := NewSelector("2-.*", MatchAnchored, AggregateSum)
+	kubelet, _ := NewSelector("kubelet", MatchRegex, AggregateSum)
+	named, _ := ParseSelectors("etcds=etcd", MatchRegex, AggregateSum)
+	claims := Claims{}
+	claims.Check("pidstat.csv", headers, etcd)
+	claims.Check("pidstat.csv", headers, second)
+	claims.Check("pidstat.csv", headers, kubelet)
+	// Headers are claimed per file
+	claims.Check("other.csv", headers[:3], named[0])
+
+	want := `Duplicate header "1-etcd" in columns 1 and 3 of pidstat.csv for pattern "etcd"
+Pattern "etcd" is ambiguous in pidstat.csv, matched 3 columns ["1-etcd" "2-etcd" "1-etcd"], using sum
+Header "2-etcd" in pidstat.csv matched by both "etcd" and "2-.*"
+`
+	if got := out.String(); got != want {
+		t.Errorf("Expected the log\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestCombineColumns(t *testing.T) {
+	nan := math.NaN()
+	columns := []Column{
+		{Name: "vda-write", Sources: []string{"vda-write"}, Values: []float64{1, 2, 3, 4}},
+		{Name: "xvda-write", Sources: []string{"xvda-write"}, Values: []float64{10, nan, 30}},
+	}
+	sum := CombineColumns(columns, "disk", AggregateSum)
+	max := CombineColumns(columns, "disk", AggregateMax)
+	if sum.Name != "disk" || len(sum.Sources) != 2 || len(sum.Values) != 3 {
+		t.Fatalf("Expected 3 rows from 2 sources, got %+v", sum)
+	}
+	if sum.Values[0] != 11 || !math.IsNaN(sum.Values[1]) || sum.Values[2] != 33 {
+		t.Errorf("Expected sums of 11, NaN and 33, got %v", sum.Values)
+	}
+	if max.Values[0] != 10 || !math.IsNaN(max.Values[1]) || max.Values[2] != 30 {
+		t.Errorf("Expected maximums of 10, NaN and 30, got %v", max.Values)
+	}
+	// The first column is not modified
+	if columns[0].Values[0] != 1 {
+		t.Errorf("CombineColumns modified its input to %v", columns[0].Values)
+	}
+	if empty := CombineColumns(nil, "disk", AggregateSum); len(empty.Values) != 0 {
+		t.Errorf("Expected no values, got %v", empty.Values)
+	}
+}
@@ -0,0 +1,103 @@
+package result
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/openshift/origin/test/extended/cluster/metrics"
+)
+
+// Metric is a metric line from the cluster-loader result.txt. Values holds
+// its fields as JSON typed values: float64, string, bool, []interface{} or
+// map[string]interface{}.
+type Metric struct {
+	Marker string `json:",omitempty"`
+	Name   string
+	Type   string
+	Values map[string]interface{}
+}
+
+// MetricDecoder turns a metric line of a known type into a Metric
+type MetricDecoder func(line []byte) (Metric, error)
+
+// MetricDecoders are the decoders of known metric types, lines of other
+// types are decoded generically by DecodeMetric
+var MetricDecoders = map[string]MetricDecoder{
+	"metrics.TestDuration": decodeTestDuration,
+}
+
+// DecodeMetric will decode a metric line with the decoder registered for its
+// type, or keep all of its fields when the type is unknown
+func DecodeMetric(line []byte) (Metric, error) {
+	var bm metrics.BaseMetrics
+	err := json.Unmarshal(line, &bm)
+	if err != nil {
+		return Metric{}, fmt.Errorf("cannot unmarshal the line '%s' for BaseMetrics: %v", line, err)
+	}
+
+	if decode, ok := MetricDecoders[bm.Type]; ok {
+		return decode(line)
+	}
+
+	var fields map[string]interface{}
+	err = json.Unmarshal(line, &fields)
+	if err != nil {
+		return Metric{}, fmt.Errorf("cannot unmarshal the line '%s' for %s: %v", line, bm.Type, err)
+	}
+	delete(fields, "marker")
+	delete(fields, "name")
+	delete(fields, "type")
+	return Metric{Marker: bm.Marker, Name: bm.Name, Type: bm.Type, Values: fields}, nil
+}
+
+// decodeTestDuration keeps the start time and the duration in seconds
+func decodeTestDuration(line []byte) (Metric, error) {
+	var td metrics.TestDuration
+	err := json.Unmarshal(line, &td)
+	if err != nil {
+		return Metric{}, fmt.Errorf("cannot unmarshal the line '%s' for TestDuration: %v", line, err)
+	}
+	return Metric{
+		Marker: td.Marker,
+		Name:   td.Name,
+		Type:   td.Type,
+		Values: map[string]interface{}{
+			"startTime":           td.StartTime.Format(time.RFC3339Nano),
+			"testDurationSeconds": td.TestDuration.Seconds(),
+		},
+	}, nil
+}
+
+// UnmarshalJSON reads a Metric, or a raw metric line as written to out.json
+// by earlier versions
+func (m *Metric) UnmarshalJSON(b []byte) error {
+	type Alias Metric
+	var a Alias
+	err := json.Unmarshal(b, &a)
+	if err != nil {
+		return err
+	}
+	if a.Values != nil {
+		*m = Metric(a)
+		return nil
+	}
+	*m, err = DecodeMetric(b)
+	return err
+}
+
+// Float returns a numeric value of the metric
+func (m Metric) Float(key string) (float64, bool) {
+	f, ok := m.Values[key].(float64)
+	return f, ok
+}
+
+// Time returns an RFC3339 time value of the metric
+func (m Metric) Time(key string) (time.Time, bool) {
+	s, ok := m.Values[key].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
@@ -0,0 +1,78 @@
+package result
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+// baselineJSON is an out.json written before Metric, with raw cluster-loader metric lines
+const baselineJSON = `{
+	"Hosts": [{"Kind": "svt-master-1", "ResultDir": "/results/svt-master-1:pbench", "Results": [
+		{"Kind": "etcd", "Path": "/results/cpu_usage_percent_cpu.csv", "Resource": "cpu_usage_percent_cpu", "Min": 1, "Max": 5, "Avg": 2.5, "Pct95": 4.75}
+	]}],
+	"Metrics": [
+		{"marker": "cluster_loader_marker", "name": "nginx", "type": "metrics.TestDuration", "startTime": "2019-10-01T10:00:00Z", "testDuration": "1m30s"},
+		{"marker": "cluster_loader_marker", "name": "pods", "type": "metrics.PodStats", "running": 42, "nodes": ["a", "b"]}
+	]
+}`
+
+func TestBaselineResultJSON(t *testing.T) {
+	var r Result
+	if err := json.Unmarshal([]byte(baselineJSON), &r); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if len(r.Hosts) != 1 || len(r.Hosts[0].Results) != 1 || r.Hosts[0].Results[0].Pct95 != 4.75 {
+		t.Fatalf("Unexpected hosts %+v", r.Hosts)
+	}
+	// Statistics the baseline did not have are missing
+	if res := r.Hosts[0].Results[0]; !math.IsNaN(res.Median) || !math.IsNaN(res.StdDev) {
+		t.Errorf("Expected NaN for statistics the baseline lacks, got %+v", res)
+	}
+	checkMetrics(t, r.Metrics)
+
+	// The decoded metrics are written and read back as they are
+	raw, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	var again Result
+	if err := json.Unmarshal(raw, &again); err != nil {
+		t.Fatalf("Unmarshal of %s returned error: %v", raw, err)
+	}
+	checkMetrics(t, again.Metrics)
+}
+
+func checkMetrics(t *testing.T, metrics []Metric) {
+	if len(metrics) != 2 {
+		t.Fatalf("Expected 2 metrics, got %+v", metrics)
+	}
+	duration := metrics[0]
+	start, ok := duration.Time("startTime")
+	seconds, ok2 := duration.Float("testDurationSeconds")
+	if duration.Name != "nginx" || duration.Marker != "cluster_loader_marker" || !ok || !ok2 ||
+		!start.Equal(time.Date(2019, 10, 1, 10, 0, 0, 0, time.UTC)) || seconds != 90 {
+		t.Errorf("Unexpected test duration %+v", duration)
+	}
+
+	// Unknown types keep all of their fields
+	pods := metrics[1]
+	running, ok := pods.Float("running")
+	nodes, _ := pods.Values["nodes"].([]interface{})
+	if pods.Type != "metrics.PodStats" || !ok || running != 42 || len(nodes) != 2 || pods.Values["type"] != nil {
+		t.Errorf("Unexpected metric %+v", pods)
+	}
+}
+
+func TestDecodeMetricErrors(t *testing.T) {
+	if _, err := DecodeMetric([]byte(`not json`)); err == nil {
+		t.Errorf("Expected an error for an invalid line")
+	}
+	if _, err := DecodeMetric([]byte(`{"type": "metrics.TestDuration", "testDuration": "forever"}`)); err == nil {
+		t.Errorf("Expected an error for an invalid duration")
+	}
+	if _, ok := (Metric{Values: map[string]interface{}{"startTime": 1.0}}).Time("startTime"); ok {
+		t.Errorf("Expected no time for a number")
+	}
+}
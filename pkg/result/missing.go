@@ -0,0 +1,110 @@
+package result
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MissingPolicy controls what happens to empty or non-numeric CSV cells
+type MissingPolicy string
+
+const (
+	// MissingSkip drops missing samples
+	MissingSkip MissingPolicy = "skip"
+	// MissingZero replaces missing samples with 0
+	MissingZero MissingPolicy = "zero"
+	// MissingFail returns an error when a sample is missing
+	MissingFail MissingPolicy = "fail"
+	// MissingInterpolate replaces missing samples by interpolating their neighbours
+	MissingInterpolate MissingPolicy = "interpolate"
+)
+
+// ParseMissingPolicy validates a missing value policy name
+func ParseMissingPolicy(policy string) (MissingPolicy, error) {
+	switch p := MissingPolicy(policy); p {
+	case MissingSkip, MissingZero, MissingFail, MissingInterpolate:
+		return p, nil
+	}
+	return "", fmt.Errorf("Unknown missing value policy %q", policy)
+}
+
+// ApplyMissing counts the missing (NaN) samples of a column and replaces or
+// drops them according to the policy
+func ApplyMissing(column Column, policy MissingPolicy) (Column, error) {
+	column.Missing = 0
+	for _, v := range column.Values {
+		if math.IsNaN(v) {
+			column.Missing++
+		}
+	}
+	if column.Missing == 0 {
+		return column, nil
+	}
+
+	switch policy {
+	case MissingFail:
+		return column, fmt.Errorf("Column %q has %d missing samples", column.Name, column.Missing)
+	case MissingZero:
+		values := make([]float64, len(column.Values))
+		for i, v := range column.Values {
+			if !math.IsNaN(v) {
+				values[i] = v
+			}
+		}
+		column.Values = values
+	case MissingInterpolate:
+		column.Values = interpolate(column.Values, column.Timestamps)
+	default:
+		var values []float64
+		var timestamps []time.Time
+		for i, v := range column.Values {
+			if math.IsNaN(v) {
+				continue
+			}
+			values = append(values, v)
+			if i < len(column.Timestamps) {
+				timestamps = append(timestamps, column.Timestamps[i])
+			}
+		}
+		column.Values = values
+		column.Timestamps = timestamps
+	}
+	return column, nil
+}
+
+// interpolate fills NaN values linearly between their valid neighbours, by time when
+// timestamps are known. Leading and trailing gaps take the nearest valid value.
+func interpolate(input []float64, timestamps []time.Time) []float64 {
+	values := make([]float64, len(input))
+	copy(values, input)
+	position := func(i int) float64 {
+		if len(timestamps) == len(values) {
+			return float64(timestamps[i].UnixNano())
+		}
+		return float64(i)
+	}
+
+	prev := -1
+	for i := 0; i <= len(values); i++ {
+		if i < len(values) && math.IsNaN(values[i]) {
+			continue
+		}
+		// Fill the gap between prev and i
+		for j := prev + 1; j < i; j++ {
+			switch {
+			case prev == -1 && i == len(values):
+				// No valid values at all, leave the gap
+			case prev == -1:
+				values[j] = values[i]
+			case i == len(values):
+				values[j] = values[prev]
+			default:
+				f := (position(j) - position(prev)) / (position(i) - position(prev))
+				values[j] = values[prev] + f*(values[i]-values[prev])
+			}
+		}
+		prev = i
+	}
+	return values
+}
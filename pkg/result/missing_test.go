@@ -0,0 +1,84 @@
+package result
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func equalValues(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.IsNaN(a[i]) != math.IsNaN(b[i]) || (!math.IsNaN(a[i]) && math.Abs(a[i]-b[i]) > 1e-9) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestApplyMissing(t *testing.T) {
+	nan := math.NaN()
+	column := Column{Name: "etcd", Values: []float64{nan, 1, nan, 3, nan}, Timestamps: []time.Time{at(0), at(10), at(20), at(30), at(40)}}
+	var tests = []struct {
+		policy MissingPolicy
+		want   []float64
+	}{
+		{MissingSkip, []float64{1, 3}},
+		{MissingZero, []float64{0, 1, 0, 3, 0}},
+		// Leading and trailing gaps take the nearest value
+		{MissingInterpolate, []float64{1, 1, 2, 3, 3}},
+	}
+	for _, v := range tests {
+		got, err := ApplyMissing(column, v.policy)
+		if err != nil {
+			t.Errorf("For %s, unexpected error %v", v.policy, err)
+		}
+		if got.Missing != 3 || !equalValues(got.Values, v.want) {
+			t.Errorf("For %s, expected %v with 3 missing instead we got %v with %d", v.policy, v.want, got.Values, got.Missing)
+		}
+		if len(got.Timestamps) != len(got.Values) {
+			t.Errorf("For %s, expected a timestamp per value, got %v", v.policy, got.Timestamps)
+		}
+	}
+	if !math.IsNaN(column.Values[0]) {
+		t.Errorf("ApplyMissing modified its input to %v", column.Values)
+	}
+
+	if _, err := ApplyMissing(column, MissingFail); err == nil {
+		t.Errorf("Expected an error for missing samples")
+	}
+	if got, err := ApplyMissing(Column{Values: []float64{1, 2}}, MissingFail); err != nil || got.Missing != 0 {
+		t.Errorf("Expected no error without missing samples, got %v", err)
+	}
+
+	// All missing samples stay missing when interpolated
+	got, _ := ApplyMissing(Column{Values: []float64{nan, nan}}, MissingInterpolate)
+	if got.Missing != 2 || !equalValues(got.Values, []float64{nan, nan}) {
+		t.Errorf("Expected 2 missing samples, got %v with %d", got.Values, got.Missing)
+	}
+	got, _ = ApplyMissing(Column{Values: []float64{nan, nan}}, MissingSkip)
+	if len(got.Values) != 0 {
+		t.Errorf("Expected no values, got %v", got.Values)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	nan := math.NaN()
+	values := []float64{0, nan, nan, 30}
+	// By index the gap is filled evenly
+	if got := interpolate(values, nil); !equalValues(got, []float64{0, 10, 20, 30}) {
+		t.Errorf("Expected interpolation by index, got %v", got)
+	}
+	// By time the samples are placed at their timestamps
+	if got := interpolate(values, []time.Time{at(0), at(1), at(2), at(10)}); !equalValues(got, []float64{0, 3, 6, 30}) {
+		t.Errorf("Expected interpolation by time, got %v", got)
+	}
+	if !math.IsNaN(values[1]) {
+		t.Errorf("interpolate modified its input to %v", values)
+	}
+	if _, err := ParseMissingPolicy("drop"); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+}
@@ -1,115 +1,518 @@
 package result
 
 import (
-	"fmt"
+	"encoding/json"
 	"log"
-	"regexp"
+	"math"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/openshift-scale/perf-analyzer/pkg/stats"
-	"github.com/openshift/origin/test/extended/cluster/metrics"
 )
 
 // Result struct contains the host results as well as the metrics
 type Result struct {
-	Hosts   []Host
-	Metrics []metrics.Metrics
+	Provenance *Provenance `json:",omitempty"`
+	Run        *RunInfo    `json:",omitempty"`
+	Tags       Tags        `json:",omitempty"`
+	Hosts      []Host
+	Metrics    []Metric
+}
+
+// Provenance records how a Result was created so it can be traced back to its sources
+type Provenance struct {
+	Version   string
+	Arguments []string
+	SearchDir string
+	Created   time.Time
+	// FirstSample and LastSample are the earliest and latest timestamps of all source files
+	FirstSample time.Time
+	LastSample  time.Time
+	// PercentileMethod estimated the percentiles and medians of the results
+	PercentileMethod string `json:",omitempty"`
+	// Stream results estimated their percentiles with sketches of SketchAccuracy instead of PercentileMethod
+	Stream         bool    `json:",omitempty"`
+	SketchAccuracy float64 `json:",omitempty"`
+	// SteadyOnly results were computed within the Steady window of each host
+	SteadyOnly bool `json:",omitempty"`
+	Files      []SourceFile
+}
+
+// SourceFile is a CSV file results were read from
+type SourceFile struct {
+	Path   string
+	SHA256 string
+	Rows   int
+}
+
+// RunInfo is the description of a run from the pbench metadata.log
+type RunInfo struct {
+	Name       string `json:",omitempty"`
+	Controller string `json:",omitempty"`
+	Date       string `json:",omitempty"`
+	// Config is the user supplied configuration name of the run
+	Config string `json:",omitempty"`
+}
+
+// HostInfo is the hardware and software of a host from the pbench sysinfo
+type HostInfo struct {
+	CPUModel string `json:",omitempty"`
+	Cores    int    `json:",omitempty"`
+	MemoryKB int64  `json:",omitempty"`
+	Kernel   string `json:",omitempty"`
+	OS       string `json:",omitempty"`
+}
+
+// SameHardware reports whether two hosts have the same CPU model, core count and memory
+func (i HostInfo) SameHardware(o HostInfo) bool {
+	return i.CPUModel == o.CPUModel && i.Cores == o.Cores && i.MemoryKB == o.MemoryKB
 }
 
 // Host struct of a Kind has a ResultDir and a list of Results
 type Host struct {
 	Kind      string
-	ResultDir string `json:",omitempty"`
-	Results   []ResultType
+	ResultDir string    `json:",omitempty"`
+	Info      *HostInfo `json:",omitempty"`
+	// Steady is the detected steady state of the host, after its warm-up and before its cool-down
+	Steady  *Window `json:",omitempty"`
+	Results []ResultType
 }
 
+// Directions tell whether a lower or a higher result is an improvement
+const (
+	DirectionNone   = ""
+	DirectionLower  = "lower"
+	DirectionHigher = "higher"
+)
+
 // ResultType is a single Result summary
 type ResultType struct {
 	Kind                 string
-	Path                 string `json:"-"`
+	Path                 string   `json:"-"`
+	Sources              []string `json:",omitempty"`
 	Resource             string
+	Phase                string `json:",omitempty"`
+	Samples              int
+	Missing              int
+	Unit                 string `json:",omitempty"`
+	Direction            string `json:",omitempty"`
 	Min, Max, Avg, Pct95 float64
+	// Variance and StdDev are of the sample, CV is StdDev relative to Avg
+	Median, StdDev, Variance, CV float64
+	// Percentiles are keyed by PercentileName, ie. p99.9
+	Percentiles map[string]float64 `json:",omitempty"`
+	// Sketch of the values, to merge percentiles across hosts or runs
+	Sketch *stats.Sketch `json:",omitempty"`
+	// Histogram of the values, to compare the shape of their distribution
+	Histogram *stats.Histogram `json:",omitempty"`
+	// TimeWeightedAvg weighs the mean of each pair of consecutive samples by the
+	// time between them, the trapezoidal rule. Integral is in Unit times seconds
+	// over Duration seconds.
+	TimeWeightedAvg, Integral, Duration float64
+	// SlopePerHour of a Theil-Sen fit over time, its R2 and its value at the
+	// end of the window, a growing RSS is a memory leak
+	SlopePerHour, R2, TrendEnd float64
+	Outliers                   *Outliers  `json:",omitempty"`
+	Above                      *Threshold `json:",omitempty"`
+	// ChangePoints are the shifts of the mean over time, ie. a step after a warm
+	// up, null when they were not searched
+	ChangePoints []ChangePoint
 }
 
-// ToSlice helps us print the Host struct data to a CSV row
-func (h *Host) ToSlice(stat string) (row []string) {
-	row = append(row, h.Kind)
-	switch stat {
+// Outliers are the spikes of a result, the runs of samples outside the fences
+// of the IQR or MAD method
+type Outliers struct {
+	Method    string
+	Low, High float64
+	// Count and Seconds cover every spike, Spikes lists the first MaxSpikes
+	Count   int
+	Seconds float64
+	Spikes  []Spike `json:",omitempty"`
+}
+
+// MaxSpikes bounds the spikes listed in Outliers
+const MaxSpikes = 100
+
+// Spike is a run of outliers, Peak is the sample furthest from the fences
+type Spike struct {
+	Start, End time.Time
+	Peak       float64
+}
+
+// Threshold is the time a result spent above a value
+type Threshold struct {
+	Value    float64
+	Seconds  float64
+	Fraction float64
+}
+
+// ChangePoint is a shift of the mean of a result at Time, from Before to After
+type ChangePoint struct {
+	Time          time.Time
+	Before, After float64
+}
+
+// Key identifies a result by the resource named after its source file, its
+// column and its phase, independently of its position in Host.Results
+type Key struct {
+	Resource, Kind, Phase string
+}
+
+// Key returns the key of a result
+func (r ResultType) Key() Key {
+	return Key{Resource: r.Resource, Kind: r.Kind, Phase: r.Phase}
+}
+
+// PercentileName names a percentile in ResultType.Percentiles and out.csv, ie. p99.9
+func PercentileName(percent float64) string {
+	return "p" + strconv.FormatFloat(percent, 'f', -1, 64)
+}
+
+// Stat returns a statistic by its out.csv name: min, mean, median, max,
+// stddev, variance, cv, time-avg, integral, slope, r2, trend-end, spikes,
+// spike-seconds, above-seconds, change-points, p95 or any name in Percentiles
+func (r ResultType) Stat(name string) (float64, bool) {
+	switch name {
 	case "min":
-		// append minimum
-		for _, result := range h.Results {
-			row = append(row, strconv.FormatFloat(result.Min, 'f', 2, 64))
-		}
+		return r.Min, true
 	case "mean":
-		// appened mean
-		for _, result := range h.Results {
-			row = append(row, strconv.FormatFloat(result.Avg, 'f', 2, 64))
-		}
+		return r.Avg, true
+	case "median", "p50":
+		return r.Median, true
 	case "p95":
-		// append p95
-		for _, result := range h.Results {
-			row = append(row, strconv.FormatFloat(result.Pct95, 'f', 2, 64))
-		}
+		return r.Pct95, true
 	case "max":
-		// append max
-		for _, result := range h.Results {
-			row = append(row, strconv.FormatFloat(result.Max, 'f', 2, 64))
+		return r.Max, true
+	case "stddev":
+		return r.StdDev, true
+	case "variance":
+		return r.Variance, true
+	case "cv":
+		return r.CV, true
+	case "time-avg":
+		return r.TimeWeightedAvg, true
+	case "integral":
+		return r.Integral, true
+	case "slope":
+		return r.SlopePerHour, true
+	case "r2":
+		return r.R2, true
+	case "trend-end":
+		return r.TrendEnd, true
+	case "spikes":
+		if r.Outliers == nil {
+			return math.NaN(), true
+		}
+		return float64(r.Outliers.Count), true
+	case "spike-seconds":
+		if r.Outliers == nil {
+			return math.NaN(), true
+		}
+		return r.Outliers.Seconds, true
+	case "above-seconds":
+		if r.Above == nil {
+			return math.NaN(), true
+		}
+		return r.Above.Seconds, true
+	case "change-points":
+		if r.ChangePoints == nil {
+			return math.NaN(), true
+		}
+		return float64(len(r.ChangePoints)), true
+	}
+	value, ok := r.Percentiles[name]
+	return value, ok
+}
+
+// clearStats sets every statistic of a result to NaN
+func (r *ResultType) clearStats() {
+	r.Min, r.Max, r.Avg, r.Pct95 = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+	r.Median, r.StdDev, r.Variance, r.CV = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+	r.TimeWeightedAvg, r.Integral, r.Duration = math.NaN(), math.NaN(), math.NaN()
+	r.SlopePerHour, r.R2, r.TrendEnd = math.NaN(), math.NaN(), math.NaN()
+	percentiles := map[string]float64{}
+	for name := range r.Percentiles {
+		percentiles[name] = math.NaN()
+	}
+	r.Percentiles = percentiles
+	r.Sketch, r.Histogram, r.Outliers, r.Above, r.ChangePoints = nil, nil, nil, nil, nil
+}
+
+// MarshalJSON writes statistics without a value (NaN) as null
+func (r ResultType) MarshalJSON() ([]byte, error) {
+	type Alias ResultType
+	s := struct {
+		Alias
+		Min, Max, Avg, Pct95         *float64
+		Median, StdDev, Variance, CV *float64
+		Percentiles                  map[string]*float64 `json:",omitempty"`
+
+		TimeWeightedAvg, Integral, Duration *float64
+		SlopePerHour, R2, TrendEnd          *float64
+	}{
+		Alias:    (Alias)(r),
+		Min:      nullable(r.Min),
+		Max:      nullable(r.Max),
+		Avg:      nullable(r.Avg),
+		Pct95:    nullable(r.Pct95),
+		Median:   nullable(r.Median),
+		StdDev:   nullable(r.StdDev),
+		Variance: nullable(r.Variance),
+		CV:       nullable(r.CV),
+
+		TimeWeightedAvg: nullable(r.TimeWeightedAvg),
+		Integral:        nullable(r.Integral),
+		Duration:        nullable(r.Duration),
+		SlopePerHour:    nullable(r.SlopePerHour),
+		R2:              nullable(r.R2),
+		TrendEnd:        nullable(r.TrendEnd),
+	}
+	if r.Percentiles != nil {
+		s.Percentiles = map[string]*float64{}
+		for name, value := range r.Percentiles {
+			s.Percentiles[name] = nullable(value)
+		}
+	}
+	return json.Marshal(&s)
+}
+
+// UnmarshalJSON reads null statistics back as NaN
+func (r *ResultType) UnmarshalJSON(b []byte) error {
+	type Alias ResultType
+	s := &struct {
+		*Alias
+		Min, Max, Avg, Pct95         *float64
+		Median, StdDev, Variance, CV *float64
+		Percentiles                  map[string]*float64
+
+		TimeWeightedAvg, Integral, Duration *float64
+		SlopePerHour, R2, TrendEnd          *float64
+	}{
+		Alias: (*Alias)(r),
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	r.Min = fromNullable(s.Min)
+	r.Max = fromNullable(s.Max)
+	r.Avg = fromNullable(s.Avg)
+	r.Pct95 = fromNullable(s.Pct95)
+	r.Median = fromNullable(s.Median)
+	r.StdDev = fromNullable(s.StdDev)
+	r.Variance = fromNullable(s.Variance)
+	r.CV = fromNullable(s.CV)
+	r.TimeWeightedAvg = fromNullable(s.TimeWeightedAvg)
+	r.Integral = fromNullable(s.Integral)
+	r.Duration = fromNullable(s.Duration)
+	r.SlopePerHour = fromNullable(s.SlopePerHour)
+	r.R2 = fromNullable(s.R2)
+	r.TrendEnd = fromNullable(s.TrendEnd)
+	r.Percentiles = nil
+	if s.Percentiles != nil {
+		r.Percentiles = map[string]float64{}
+		for name, value := range s.Percentiles {
+			r.Percentiles[name] = fromNullable(value)
+		}
+	}
+	return nil
+}
+
+func nullable(f float64) *float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+func fromNullable(f *float64) float64 {
+	if f == nil {
+		return math.NaN()
+	}
+	return *f
+}
+
+// Lookup returns the position of the result with the given key
+func (h *Host) Lookup(k Key) (int, bool) {
+	for i := range h.Results {
+		if h.Results[i].Key() == k {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Align gives every host the same results in the same order, the union of the
+// results of all hosts. Results a host doesn't have are added without values,
+// only the first of several results of a host with the same key is kept.
+func Align(hosts []Host) {
+	var keys []Key
+	template := map[Key]ResultType{}
+	for _, h := range hosts {
+		seen := map[Key]bool{}
+		for _, r := range h.Results {
+			if seen[r.Key()] {
+				log.Printf("Dropping duplicate result %+v of %s from %v\n", r.Key(), h.Kind, r.Sources)
+			}
+			seen[r.Key()] = true
+			if _, ok := template[r.Key()]; !ok {
+				keys = append(keys, r.Key())
+				template[r.Key()] = r
+			}
 		}
-	default:
-		// do nothing
+	}
+
+	for i := range hosts {
+		results := make([]ResultType, 0, len(keys))
+		for _, k := range keys {
+			if j, ok := hosts[i].Lookup(k); ok {
+				results = append(results, hosts[i].Results[j])
+				continue
+			}
+			empty := template[k]
+			empty.Path, empty.Sources = "", nil
+			empty.Samples, empty.Missing = 0, 0
+			empty.clearStats()
+			results = append(results, empty)
+		}
+		hosts[i].Results = results
+	}
+}
+
+// ToSlice helps us print the Host struct data to a CSV row, a result without
+// a value is an empty cell
+func (h *Host) ToSlice(stat string) (row []string) {
+	row = append(row, h.Kind)
+	for _, result := range h.Results {
+		value, ok := result.Stat(stat)
+		if !ok {
+			value = math.NaN()
+		}
+		row = append(row, formatValue(value))
 	}
 	return
 }
 
-// AddResult will create a new ResultType which is added to a Host
-func (h *Host) AddResult(newResult []float64, file string, kind string, res string) []ResultType {
-	min, _ := stats.Minimum(newResult)
-	max, _ := stats.Maximum(newResult)
-	avg, _ := stats.Mean(newResult)
-	pct95, _ := stats.Percentile(newResult, 95)
+// StatNames lists the statistics written to out.csv, including every
+// percentile found in the results of the hosts, ordered by percent
+func StatNames(hosts []Host) []string {
+	percents := []float64{95}
+	seen := map[string]bool{PercentileName(95): true}
+	for _, h := range hosts {
+		for _, r := range h.Results {
+			for name := range r.Percentiles {
+				percent, err := strconv.ParseFloat(strings.TrimPrefix(name, "p"), 64)
+				if err == nil && !seen[name] {
+					seen[name] = true
+					percents = append(percents, percent)
+				}
+			}
+		}
+	}
+	sort.Float64s(percents)
+
+	names := []string{"min", "mean", "median"}
+	for _, percent := range percents {
+		names = append(names, PercentileName(percent))
+	}
+	return append(names, "max", "stddev", "variance", "cv", "time-avg", "integral", "slope", "r2", "trend-end", "spikes", "spike-seconds", "above-seconds", "change-points")
+}
+
+func formatValue(f float64) string {
+	if math.IsNaN(f) {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+// SetSummary copies the statistics of a summary into a result
+func (r *ResultType) SetSummary(s stats.Summary) {
+	r.Samples = s.Count
+	r.Min, r.Max, r.Avg, r.Pct95 = s.Min, s.Max, s.Mean, s.Pct95
+	r.Median, r.StdDev, r.Variance, r.CV = s.Median, s.StdDev, s.Variance, s.CV
+	r.TimeWeightedAvg, r.Integral, r.Duration = s.TimeWeightedMean, s.Integral, s.Duration
+	r.SlopePerHour, r.R2, r.TrendEnd = s.SlopePerHour, s.R2, s.TrendEnd
+	r.Percentiles = map[string]float64{}
+	for percent, value := range s.Percentiles {
+		r.Percentiles[PercentileName(percent)] = value
+	}
+}
 
-	h.Results = append(h.Results, ResultType{
+// SetOutliers records the spikes of a result found outside the fences low and
+// high, with their times in seconds since the epoch
+func (r *ResultType) SetOutliers(method string, low, high float64, spikes []stats.Spike) {
+	r.Outliers = &Outliers{Method: method, Low: low, High: high, Count: len(spikes)}
+	for _, spike := range spikes {
+		r.Outliers.Seconds += spike.End - spike.Start
+		if len(r.Outliers.Spikes) < MaxSpikes {
+			r.Outliers.Spikes = append(r.Outliers.Spikes, Spike{Start: Epoch(spike.Start), End: Epoch(spike.End), Peak: spike.Peak})
+		}
+	}
+}
+
+// SetAbove records the time a result spent above a threshold
+func (r *ResultType) SetAbove(th stats.Threshold) {
+	r.Above = &Threshold{Value: th.Value, Seconds: th.Seconds, Fraction: th.Fraction()}
+}
+
+// SetChangePoints records the shifts of the mean of a result, with their times
+// in seconds since the epoch. A result searched without any shift has an empty
+// list so that it is told apart from a result that was not searched.
+func (r *ResultType) SetChangePoints(points []stats.ChangePoint) {
+	r.ChangePoints = []ChangePoint{}
+	for _, point := range points {
+		r.ChangePoints = append(r.ChangePoints, ChangePoint{Time: Epoch(point.Seconds), Before: point.Before, After: point.After})
+	}
+}
+
+// Epoch returns the UTC time of seconds since the epoch
+func Epoch(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC()
+}
+
+// AddSummary will create a new ResultType from the statistics of a column which is added to a Host
+func (h *Host) AddSummary(summary stats.Summary, file string, kind string, res string) []ResultType {
+	r := ResultType{
 		Kind:     kind,
 		Path:     file,
 		Resource: strings.TrimSuffix(res, ".csv"),
-		Min:      min,
-		Max:      max,
-		Avg:      avg,
-		Pct95:    pct95,
-	})
+	}
+	r.SetSummary(summary)
+	h.Results = append(h.Results, r)
 
 	return h.Results
-
 }
 
-// NewSlice will extract a single slice of values from a CSV
-func NewSlice(bigSlice [][]string, title string) ([]float64, error) {
-	floatValues := make([]float64, len(bigSlice)-1)
-	var column int
-	for i, v := range bigSlice {
+// columnValues will extract a single column of values from a CSV, empty and
+// non-numeric cells are counted and stored as NaN
+func columnValues(bigSlice [][]string, column int) (floatValues []float64, empty, invalid int) {
+	floatValues = make([]float64, len(bigSlice)-1)
+	for i := range bigSlice {
 		if i == 0 {
-			var err error
-			column, err = stringPositionInSlice(title, v)
-			if err != nil {
-				log.Println(err)
-				return nil, err
-			}
 			continue
 		}
-		value, _ := strconv.ParseFloat(bigSlice[i][column], 64)
+		value, isEmpty, isInvalid := ParseSample(bigSlice[i][column])
+		if isEmpty {
+			empty++
+		}
+		if isInvalid {
+			invalid++
+		}
 		floatValues[i-1] = value
 	}
-	return floatValues, nil
+	return
 }
 
-// TODO: handle duplicates or none
-func stringPositionInSlice(a string, list []string) (int, error) {
-	for i, v := range list {
-		match, _ := regexp.MatchString(a, v)
-		if match {
-			return i, nil
-		}
+// ParseSample parses a CSV cell, an empty or non-numeric cell is NaN
+func ParseSample(cell string) (value float64, empty, invalid bool) {
+	cell = strings.TrimSpace(cell)
+	value, err := strconv.ParseFloat(cell, 64)
+	switch {
+	case cell == "":
+		return math.NaN(), true, false
+	case err != nil || math.IsNaN(value) || math.IsInf(value, 0):
+		return math.NaN(), false, true
 	}
-	return 0, fmt.Errorf("No matching headers")
+	return value, false, false
 }
@@ -0,0 +1,87 @@
+package result
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	hosts := []Host{
+		{Kind: "master", Results: []ResultType{
+			{Kind: "sda-write", Resource: "disk_IOPS", Min: 1, Max: 1, Avg: 1, Pct95: 1},
+			{Kind: "etcd", Resource: "cpu_usage_percent_cpu", Min: 2, Max: 2, Avg: 2, Pct95: 2},
+		}},
+		{Kind: "node", Results: []ResultType{
+			{Kind: "etcd", Resource: "cpu_usage_percent_cpu", Min: 3, Max: 3, Avg: 3, Pct95: 3},
+			{Kind: "vda-write", Resource: "disk_IOPS", Min: 4, Max: 4, Avg: 4, Pct95: 4},
+		}},
+	}
+	Align(hosts)
+
+	expected := []string{"sda-write", "etcd", "vda-write"}
+	for _, h := range hosts {
+		if len(h.Results) != len(expected) {
+			t.Fatalf("For %v, expected %v results instead we got %v", h.Kind, len(expected), len(h.Results))
+		}
+		for i, kind := range expected {
+			if h.Results[i].Kind != kind {
+				t.Errorf("For %v, expected %v at %v instead we got %v", h.Kind, kind, i, h.Results[i].Kind)
+			}
+		}
+	}
+	if !math.IsNaN(hosts[0].Results[2].Pct95) || !math.IsNaN(hosts[1].Results[0].Pct95) {
+		t.Errorf("Expected NaN for results a host doesn't have, instead we got %v and %v", hosts[0].Results[2].Pct95, hosts[1].Results[0].Pct95)
+	}
+}
+
+func TestResultTypeJSON(t *testing.T) {
+	in := ResultType{Kind: "etcd", Resource: "disk_IOPS", Min: 1, Max: math.NaN(), Avg: 2, Pct95: math.NaN()}
+	raw, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+
+	var out ResultType
+	err = json.Unmarshal(raw, &out)
+	if err != nil {
+		t.Fatalf("Unmarshal of %s returned error: %v", raw, err)
+	}
+	if out.Key() != in.Key() || out.Min != 1 || out.Avg != 2 || !math.IsNaN(out.Max) || !math.IsNaN(out.Pct95) {
+		t.Errorf("For %s, expected %+v instead we got %+v", raw, in, out)
+	}
+}
+
+func TestChangePointsJSON(t *testing.T) {
+	var in ResultType
+	in.SetChangePoints(nil)
+	raw, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	var out ResultType
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatalf("Unmarshal of %s returned error: %v", raw, err)
+	}
+	// A result without change points is told apart from one that was not searched
+	if count, _ := out.Stat("change-points"); count != 0 {
+		t.Errorf("For %s, expected 0 change points instead we got %v", raw, count)
+	}
+	if count, _ := (ResultType{}).Stat("change-points"); !math.IsNaN(count) {
+		t.Errorf("Expected no change points when not searched, got %v", count)
+	}
+}
+
+func TestAlignDuplicates(t *testing.T) {
+	hosts := []Host{
+		{Kind: "master", Results: []ResultType{
+			{Kind: "etcd", Resource: "cpu_usage_percent_cpu", Avg: 1},
+			{Kind: "etcd", Resource: "cpu_usage_percent_cpu", Avg: 2},
+			{Kind: "etcd", Resource: "cpu_usage_percent_cpu", Phase: "steady", Avg: 3},
+		}},
+	}
+	Align(hosts)
+	if len(hosts[0].Results) != 2 || hosts[0].Results[0].Avg != 1 || hosts[0].Results[1].Avg != 3 {
+		t.Errorf("Expected the first result of each key to be kept, got %+v", hosts[0].Results)
+	}
+}
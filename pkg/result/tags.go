@@ -0,0 +1,84 @@
+package result
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Tags are user supplied key=value labels of a run, ie. OCP version or network
+// plugin. Tags is a flag.Value so the flag can be repeated.
+type Tags map[string]string
+
+// String returns the tags as sorted key=value pairs
+func (t Tags) String() string {
+	var pairs []string
+	for _, k := range t.Keys() {
+		pairs = append(pairs, k+"="+t[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds a key=value tag
+func (t Tags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("Tag %q is not key=value", value)
+	}
+	t[kv[0]] = kv[1]
+	return nil
+}
+
+// Keys returns the sorted tag keys
+func (t Tags) Keys() []string {
+	var keys []string
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Matches reports whether every tag of want has the same value in t
+func (t Tags) Matches(want Tags) bool {
+	for k, v := range want {
+		if value, ok := t[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Without returns a copy of the tags without the given keys
+func (t Tags) Without(keys ...string) Tags {
+	kept := Tags{}
+	for k, v := range t {
+		kept[k] = v
+	}
+	for _, k := range keys {
+		delete(kept, k)
+	}
+	return kept
+}
+
+// Diff describes every key with a different value in the two tag sets,
+// a key missing from one side counts as different
+func (t Tags) Diff(o Tags) []string {
+	keys := Tags{}
+	for k := range t {
+		keys[k] = ""
+	}
+	for k := range o {
+		keys[k] = ""
+	}
+
+	var diff []string
+	for _, k := range keys.Keys() {
+		a, aok := t[k]
+		b, bok := o[k]
+		if a != b || aok != bok {
+			diff = append(diff, fmt.Sprintf("%s: %q => %q", k, a, b))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,58 @@
+package result
+
+import (
+	"testing"
+)
+
+func TestTagsSet(t *testing.T) {
+	tags := Tags{}
+	for _, value := range []string{"ocp=4.2", "sdn=ovn", "ocp=4.3", "empty=", "url=http://a?b=c"} {
+		if err := tags.Set(value); err != nil {
+			t.Errorf("For %q, unexpected error %v", value, err)
+		}
+	}
+	// A repeated key keeps its last value
+	if tags.String() != "empty=,ocp=4.3,sdn=ovn,url=http://a?b=c" {
+		t.Errorf("Unexpected tags %s", tags)
+	}
+	for _, value := range []string{"ocp", "=4.2", ""} {
+		if err := tags.Set(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestTagsMatches(t *testing.T) {
+	tags := Tags{"ocp": "4.2", "platform": "aws"}
+	if !tags.Matches(Tags{"platform": "aws"}) || !tags.Matches(Tags{}) {
+		t.Errorf("Expected %s to match", tags)
+	}
+	if tags.Matches(Tags{"platform": "gcp"}) || tags.Matches(Tags{"sdn": ""}) {
+		t.Errorf("Expected %s not to match a different or missing tag", tags)
+	}
+}
+
+func TestTagsDiff(t *testing.T) {
+	old := Tags{"ocp": "4.2", "platform": "aws", "build": "1"}
+	new := Tags{"ocp": "4.3", "platform": "aws", "sdn": ""}
+	diff := old.Diff(new)
+	want := []string{`build: "1" => ""`, `ocp: "4.2" => "4.3"`, `sdn: "" => ""`}
+	if len(diff) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, diff)
+	}
+	for i := range want {
+		if diff[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, diff)
+			break
+		}
+	}
+
+	// Ignored keys are expected to differ
+	ignored := []string{"ocp", "build", "sdn"}
+	if diff := old.Without(ignored...).Diff(new.Without(ignored...)); len(diff) != 0 {
+		t.Errorf("Expected no difference without the ignored keys, got %v", diff)
+	}
+	if len(old) != 3 {
+		t.Errorf("Without modified the tags to %s", old)
+	}
+}
@@ -0,0 +1,137 @@
+package result
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Window limits the samples used for results to a time range, a zero Start
+// or End leaves that side of the window open
+type Window struct {
+	Start, End time.Time
+}
+
+// Contains reports whether a timestamp is inside the window
+func (w Window) Contains(t time.Time) bool {
+	if !w.Start.IsZero() && t.Before(w.Start) {
+		return false
+	}
+	if !w.End.IsZero() && t.After(w.End) {
+		return false
+	}
+	return true
+}
+
+// Trim narrows the window to skip a duration after first and before last,
+// the window is only ever narrowed
+func (w Window) Trim(first, last time.Time, skipStart, skipEnd time.Duration) Window {
+	start := first.Add(skipStart)
+	if w.Start.IsZero() || start.After(w.Start) {
+		w.Start = start
+	}
+	end := last.Add(-skipEnd)
+	if w.End.IsZero() || end.Before(w.End) {
+		w.End = end
+	}
+	return w
+}
+
+// Timestamps parses the timestamp_ms column of a CSV, one per data row
+func Timestamps(bigSlice [][]string) ([]time.Time, error) {
+	if len(bigSlice) == 0 {
+		return nil, fmt.Errorf("No header row")
+	}
+	column, err := TimestampColumn(bigSlice[0])
+	if err != nil {
+		return nil, err
+	}
+
+	timestamps := make([]time.Time, len(bigSlice)-1)
+	for i, row := range bigSlice[1:] {
+		timestamps[i], err = ParseTimestamp(row[column])
+		if err != nil {
+			return nil, fmt.Errorf("%v on row %d", err, i+1)
+		}
+	}
+	return timestamps, nil
+}
+
+// TimestampColumn returns the index of the timestamp_ms header
+func TimestampColumn(headers []string) (int, error) {
+	for i, h := range headers {
+		if h == timestampHeader {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("No %s column", timestampHeader)
+}
+
+// ParseTimestamp parses a timestamp_ms cell
+func ParseTimestamp(cell string) (time.Time, error) {
+	ms, err := strconv.ParseInt(cell, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid timestamp %q: %v", cell, err)
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)), nil
+}
+
+// TimeSpan returns the first and last timestamp of a CSV
+func TimeSpan(bigSlice [][]string) (first, last time.Time, err error) {
+	timestamps, err := Timestamps(bigSlice)
+	if err != nil {
+		return
+	}
+	for _, t := range timestamps {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if last.IsZero() || t.After(last) {
+			last = t
+		}
+	}
+	return
+}
+
+// SliceWindow returns the header and the rows of a CSV within the window, ordered by timestamp.
+// A CSV without timestamps is returned unchanged.
+func SliceWindow(bigSlice [][]string, w Window) ([][]string, error) {
+	timestamps, err := Timestamps(bigSlice)
+	if err != nil {
+		return bigSlice, err
+	}
+
+	var rows []int
+	for i, t := range timestamps {
+		if w.Contains(t) {
+			rows = append(rows, i)
+		}
+	}
+	sort.SliceStable(rows, func(a, b int) bool {
+		return timestamps[rows[a]].Before(timestamps[rows[b]])
+	})
+
+	windowed := [][]string{bigSlice[0]}
+	for _, i := range rows {
+		windowed = append(windowed, bigSlice[i+1])
+	}
+	return windowed, nil
+}
+
+// Intersect returns the part of the window also inside another window
+func (w Window) Intersect(o Window) Window {
+	if w.Start.IsZero() || o.Start.After(w.Start) {
+		w.Start = o.Start
+	}
+	if w.End.IsZero() || (!o.End.IsZero() && o.End.Before(w.End)) {
+		w.End = o.End
+	}
+	return w
+}
+
+// Phase is a named part of a run, ie. creating projects or steady state
+type Phase struct {
+	Name string
+	Window
+}
@@ -0,0 +1,86 @@
+package result
+
+import (
+	"testing"
+	"time"
+)
+
+func at(seconds int) time.Time {
+	return time.Unix(int64(seconds), 0)
+}
+
+func TestWindowTrim(t *testing.T) {
+	w := Window{}.Trim(at(0), at(100), 10*time.Second, 20*time.Second)
+	if !w.Start.Equal(at(10)) || !w.End.Equal(at(80)) {
+		t.Errorf("Expected a window from 10s to 80s, got %v to %v", w.Start, w.End)
+	}
+
+	// The window is only ever narrowed
+	w = Window{Start: at(30), End: at(50)}.Trim(at(0), at(100), 10*time.Second, 20*time.Second)
+	if !w.Start.Equal(at(30)) || !w.End.Equal(at(50)) {
+		t.Errorf("Expected the window from 30s to 50s to be kept, got %v to %v", w.Start, w.End)
+	}
+
+	// Skipping more than the span leaves no sample
+	w = Window{}.Trim(at(0), at(100), 80*time.Second, 80*time.Second)
+	for s := 0; s <= 100; s += 10 {
+		if w.Contains(at(s)) {
+			t.Errorf("Expected no sample in %v to %v, got %vs", w.Start, w.End, s)
+		}
+	}
+}
+
+func TestWindowIntersect(t *testing.T) {
+	w := Window{Start: at(0), End: at(50)}.Intersect(Window{Start: at(20)})
+	if !w.Start.Equal(at(20)) || !w.End.Equal(at(50)) {
+		t.Errorf("Expected a window from 20s to 50s, got %v to %v", w.Start, w.End)
+	}
+	w = Window{}.Intersect(Window{End: at(40)})
+	if !w.Start.IsZero() || !w.End.Equal(at(40)) {
+		t.Errorf("Expected a window open at the start until 40s, got %v to %v", w.Start, w.End)
+	}
+
+	empty := Window{Start: at(0), End: at(10)}.Intersect(Window{Start: at(20), End: at(30)})
+	for s := 0; s <= 30; s += 5 {
+		if empty.Contains(at(s)) {
+			t.Errorf("Expected an empty intersection, got %vs in %v to %v", s, empty.Start, empty.End)
+		}
+	}
+}
+
+func TestSliceWindow(t *testing.T) {
+	rows := [][]string{
+		{"timestamp_ms", "value"},
+		{"30000", "3"},
+		{"10000", "1"},
+		{"50000", "5"},
+		{"20000", "2"},
+		{"40000", "4"},
+	}
+	windowed, err := SliceWindow(rows, Window{Start: at(20), End: at(40)})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	want := []string{"2", "3", "4"}
+	if len(windowed) != len(want)+1 || windowed[0][0] != "timestamp_ms" {
+		t.Fatalf("Expected the header and %v, got %v", want, windowed)
+	}
+	for i, value := range want {
+		if windowed[i+1][1] != value {
+			t.Errorf("Expected the rows sorted by time %v, got %v", want, windowed[1:])
+			break
+		}
+	}
+
+	// Rows without timestamps are returned unchanged
+	untimed := [][]string{{"value"}, {"2"}, {"1"}}
+	windowed, err = SliceWindow(untimed, Window{Start: at(20)})
+	if err == nil || len(windowed) != 3 || windowed[1][0] != "2" {
+		t.Errorf("Expected the rows unchanged with an error, got %v (%v)", windowed, err)
+	}
+	invalid := [][]string{{"timestamp_ms", "value"}, {"10000", "1"}, {"soon", "2"}}
+	windowed, err = SliceWindow(invalid, Window{Start: at(20)})
+	if err == nil || len(windowed) != 3 {
+		t.Errorf("Expected the rows unchanged with an error for an invalid timestamp, got %v (%v)", windowed, err)
+	}
+}
@@ -0,0 +1,133 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxChangeSamples bounds the samples searched for change points, longer series are thinned evenly
+const maxChangeSamples = 2000
+
+// minSegment is the fewest samples between two change points
+const minSegment = 5
+
+// ChangePoint is a shift of the mean of a series
+type ChangePoint struct {
+	// Seconds is the time of the first sample after the shift
+	Seconds float64
+	// Before and After are the means of the segments on either side
+	Before, After float64
+}
+
+// ChangePoints finds the shifts of the mean of values sampled at times in
+// seconds with PELT (Killick et al. 2012). The cost of a segment is its sum of
+// squares scaled by the noise variance, estimated from the differences of
+// consecutive samples so the shifts do not inflate it, and every change point
+// costs penalty times the log of the number of samples, 2 being the BIC.
+func ChangePoints(seconds, values []float64, penalty float64) ([]ChangePoint, error) {
+	if len(seconds) != len(values) {
+		return nil, fmt.Errorf("Got %d times for %d values", len(seconds), len(values))
+	}
+	if penalty <= 0 {
+		return nil, fmt.Errorf("Invalid penalty %v, expected a positive number", penalty)
+	}
+	var times, samples []float64
+	for i := range values {
+		if !math.IsNaN(values[i]) && !math.IsInf(values[i], 0) {
+			times = append(times, seconds[i])
+			samples = append(samples, values[i])
+		}
+	}
+	times, samples = thin(times, samples, maxChangeSamples)
+	n := len(samples)
+	if n < 2*minSegment {
+		return nil, nil
+	}
+	variance := noiseVariance(samples)
+	if variance == 0 {
+		return nil, nil
+	}
+
+	sums := make([]float64, n+1)
+	squares := make([]float64, n+1)
+	for i, x := range samples {
+		sums[i+1] = sums[i] + x
+		squares[i+1] = squares[i] + x*x
+	}
+	// cost of the segment of samples s to t-1
+	cost := func(s, t int) float64 {
+		sum := sums[t] - sums[s]
+		return (squares[t] - squares[s] - sum*sum/float64(t-s)) / variance
+	}
+
+	beta := penalty * math.Log(float64(n))
+	best := make([]float64, n+1)
+	last := make([]int, n+1)
+	best[0] = -beta
+	candidates := []int{0}
+	for t := 1; t <= n; t++ {
+		best[t] = math.Inf(1)
+		for _, s := range candidates {
+			if t-s < minSegment {
+				continue
+			}
+			if f := best[s] + cost(s, t) + beta; f < best[t] {
+				best[t], last[t] = f, s
+			}
+		}
+		// Candidates that cannot start the last segment of a better segmentation are pruned
+		var kept []int
+		for _, s := range candidates {
+			if t-s < minSegment || best[s]+cost(s, t) <= best[t] {
+				kept = append(kept, s)
+			}
+		}
+		candidates = kept
+		if !math.IsInf(best[t], 1) {
+			candidates = append(candidates, t)
+		}
+	}
+
+	var starts []int
+	for t := last[n]; t > 0; t = last[t] {
+		starts = append([]int{t}, starts...)
+	}
+	var points []ChangePoint
+	for i, start := range starts {
+		from := 0
+		if i > 0 {
+			from = starts[i-1]
+		}
+		to := n
+		if i+1 < len(starts) {
+			to = starts[i+1]
+		}
+		points = append(points, ChangePoint{
+			Seconds: times[start],
+			Before:  (sums[start] - sums[from]) / float64(start-from),
+			After:   (sums[to] - sums[start]) / float64(to-start),
+		})
+	}
+	return points, nil
+}
+
+// noiseVariance estimates the variance of the noise of a series from the
+// median absolute deviation of its differences, falling back to its variance
+func noiseVariance(samples []float64) float64 {
+	diffs := make([]float64, len(samples)-1)
+	for i := range diffs {
+		diffs[i] = samples[i+1] - samples[i]
+	}
+	_, high, _ := Fences(diffs, OutliersMAD, 1)
+	median, _ := Median(diffs)
+	// A difference has twice the variance of the noise
+	sigma := (high - median) / math.Sqrt2
+	if sigma > 0 {
+		return sigma * sigma
+	}
+	variance, err := Variance(samples)
+	if err != nil {
+		return 0
+	}
+	return variance
+}
@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChangePoints(t *testing.T) {
+	// A step from 10 to 50 at 60s with some noise
+	var times, values []float64
+	for i := 0; i < 120; i++ {
+		times = append(times, float64(i))
+		value := 10 + math.Sin(float64(i))
+		if i >= 60 {
+			value += 40
+		}
+		values = append(values, value)
+	}
+	points, err := ChangePoints(times, values, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(points) != 1 || points[0].Seconds != 60 {
+		t.Fatalf("Expected a change point at 60s, got %v", points)
+	}
+	if math.Abs(points[0].Before-10) > 0.5 || math.Abs(points[0].After-50) > 0.5 {
+		t.Errorf("Expected means of 10 and 50 around the change point, got %+v", points[0])
+	}
+
+	// Noise alone has no change point
+	points, _ = ChangePoints(times[:60], values[:60], 2)
+	if len(points) != 0 {
+		t.Errorf("Expected no change point, got %v", points)
+	}
+	points, _ = ChangePoints([]float64{0, 1, 2}, []float64{1, 1, 1}, 2)
+	if len(points) != 0 {
+		t.Errorf("Expected no change point for few values, got %v", points)
+	}
+	if _, err := ChangePoints(times, values, 0); err == nil {
+		t.Errorf("Expected an error for a penalty of 0")
+	}
+}
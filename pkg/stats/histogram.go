@@ -0,0 +1,190 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// HistogramScale decides how the buckets of a histogram are spaced
+type HistogramScale string
+
+const (
+	// HistogramNone disables histograms
+	HistogramNone HistogramScale = "none"
+	// HistogramLinear buckets have the same width from the minimum to the maximum
+	HistogramLinear HistogramScale = "linear"
+	// HistogramLog buckets grow geometrically from the smallest positive value to
+	// the maximum, for values spanning orders of magnitude like latencies
+	HistogramLog HistogramScale = "log"
+)
+
+// ParseHistogramScale validates a histogram scale name
+func ParseHistogramScale(scale string) (HistogramScale, error) {
+	switch s := HistogramScale(scale); s {
+	case HistogramNone, HistogramLinear, HistogramLog:
+		return s, nil
+	case "":
+		return HistogramNone, nil
+	}
+	return "", fmt.Errorf("Unknown histogram scale %q", scale)
+}
+
+// Histogram counts values in buckets between Bounds, Counts[i] counts the
+// values from Bounds[i] to Bounds[i+1] and the last bucket includes its upper bound
+type Histogram struct {
+	Scale  HistogramScale
+	Bounds []float64
+	Counts []uint64
+	// Zero counts the values of at most 0 of a log histogram
+	Zero uint64 `json:",omitempty"`
+}
+
+// NewHistogram returns an empty histogram of buckets from min to max, a log
+// histogram starts at the smallest positive value instead of the minimum
+func NewHistogram(scale HistogramScale, buckets int, min, max float64) (*Histogram, error) {
+	if buckets < 1 {
+		return nil, fmt.Errorf("Invalid number of buckets %d", buckets)
+	}
+	if math.IsNaN(min) || math.IsNaN(max) || min > max {
+		return nil, fmt.Errorf("Invalid histogram range %v to %v", min, max)
+	}
+	h := &Histogram{Scale: scale}
+	switch scale {
+	case HistogramLinear:
+	case HistogramLog:
+		// Only values of at most 0 are counted
+		if max <= 0 {
+			return h, nil
+		}
+		if min <= 0 {
+			return nil, fmt.Errorf("Invalid log histogram range %v to %v, the start must be positive", min, max)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown histogram scale %q", scale)
+	}
+	if min == max {
+		buckets = 1
+	}
+
+	h.Bounds = make([]float64, buckets+1)
+	h.Counts = make([]uint64, buckets)
+	for i := range h.Bounds {
+		f := float64(i) / float64(buckets)
+		if scale == HistogramLog {
+			h.Bounds[i] = min * math.Pow(max/min, f)
+		} else {
+			h.Bounds[i] = min + (max-min)*f
+		}
+	}
+	h.Bounds[0], h.Bounds[buckets] = min, max
+	return h, nil
+}
+
+// Add counts a value n times, values outside the bounds count in the first or
+// last bucket and NaN and infinite values are ignored
+func (h *Histogram) Add(value float64, n uint64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	if h.Scale == HistogramLog && value <= 0 {
+		h.Zero += n
+		return
+	}
+	if len(h.Counts) == 0 {
+		return
+	}
+	min, max := h.Bounds[0], h.Bounds[len(h.Bounds)-1]
+	var f float64
+	if max > min {
+		if h.Scale == HistogramLog {
+			f = math.Log(value/min) / math.Log(max/min)
+		} else {
+			f = (value - min) / (max - min)
+		}
+	}
+	i := int(math.Floor(f * float64(len(h.Counts))))
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(h.Counts) {
+		i = len(h.Counts) - 1
+	}
+	h.Counts[i] += n
+}
+
+// Total returns the number of values counted
+func (h *Histogram) Total() uint64 {
+	total := h.Zero
+	for _, n := range h.Counts {
+		total += n
+	}
+	return total
+}
+
+// HistogramOf returns the histogram of a slice of float64 numbers
+func HistogramOf(input []float64, scale HistogramScale, buckets int) (*Histogram, error) {
+	var min, max, positive float64
+	count := 0
+	for _, value := range input {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		if count == 0 || value < min {
+			min = value
+		}
+		if count == 0 || value > max {
+			max = value
+		}
+		if value > 0 && (positive == 0 || value < positive) {
+			positive = value
+		}
+		count++
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("Invalid float slice: %g", input)
+	}
+	if scale == HistogramLog {
+		// Without positive values only the Zero bucket is counted, like Sketch.Histogram
+		min = max
+		if positive > 0 {
+			min = positive
+		}
+	}
+	h, err := NewHistogram(scale, buckets, min, max)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range input {
+		h.Add(value, 1)
+	}
+	return h, nil
+}
+
+// Histogram returns the histogram of the values in the sketch, every value is
+// counted at the estimate of its sketch bucket
+func (s *Sketch) Histogram(scale HistogramScale, buckets int) (*Histogram, error) {
+	if s.Count == 0 {
+		return nil, fmt.Errorf("Empty sketch")
+	}
+	min := s.Min
+	if scale == HistogramLog && min <= 0 {
+		// The smallest positive value is estimated by its bucket
+		min = s.Max
+		for _, i := range sortedIndexes(s.Positive, false) {
+			min = s.clamp(s.value(i))
+			break
+		}
+	}
+	h, err := NewHistogram(scale, buckets, min, s.Max)
+	if err != nil {
+		return nil, err
+	}
+	for i, n := range s.Negative {
+		h.Add(s.clamp(-s.value(i)), n)
+	}
+	h.Add(s.clamp(0), s.Zero)
+	for i, n := range s.Positive {
+		h.Add(s.clamp(s.value(i)), n)
+	}
+	return h, nil
+}
@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHistogramLinear(t *testing.T) {
+	h, err := HistogramOf([]float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, math.NaN()}, HistogramLinear, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	bounds := []float64{0, 2, 4, 6, 8, 10}
+	counts := []uint64{2, 2, 2, 2, 3}
+	for i := range bounds {
+		if math.Abs(h.Bounds[i]-bounds[i]) > 1e-9 {
+			t.Errorf("Expected bounds %v, got %v", bounds, h.Bounds)
+			break
+		}
+	}
+	for i := range counts {
+		if h.Counts[i] != counts[i] {
+			t.Errorf("Expected counts %v, got %v", counts, h.Counts)
+			break
+		}
+	}
+	if h.Total() != 11 {
+		t.Errorf("Expected 11 values, got %v", h.Total())
+	}
+}
+
+func TestHistogramLog(t *testing.T) {
+	h, err := HistogramOf([]float64{0, 1, 5, 10, 50, 100, 500, 1000}, HistogramLog, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if h.Zero != 1 || h.Counts[0] != 2 || h.Counts[1] != 2 || h.Counts[2] != 3 {
+		t.Errorf("Expected 1 zero and counts [2 2 3], got %v and %v", h.Zero, h.Counts)
+	}
+	if math.Abs(h.Bounds[1]-10) > 1e-9 || math.Abs(h.Bounds[2]-100) > 1e-9 {
+		t.Errorf("Expected bounds of 1, 10, 100 and 1000, got %v", h.Bounds)
+	}
+
+	// Values of at most 0 only fill the Zero bucket, from the values or their sketch
+	for _, values := range [][]float64{{-5, -1, -3}, {0, 0}} {
+		sketch, _ := NewSketch(0.01)
+		for _, value := range values {
+			sketch.Add(value)
+		}
+		fromSketch, err := sketch.Histogram(HistogramLog, 3)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		h, err := HistogramOf(values, HistogramLog, 3)
+		if err != nil {
+			t.Fatalf("For %v, unexpected error %v", values, err)
+		}
+		if h.Zero != uint64(len(values)) || len(h.Counts) != 0 || fromSketch.Zero != h.Zero || len(fromSketch.Counts) != 0 {
+			t.Errorf("For %v, expected only %d values at most 0, got %+v and %+v from the sketch", values, len(values), h, fromSketch)
+		}
+	}
+
+	if _, err := HistogramOf(nil, HistogramLog, 3); err == nil {
+		t.Errorf("Expected an error for no values")
+	}
+	if s, err := ParseHistogramScale(""); err != nil || s != HistogramNone {
+		t.Errorf("Expected no histogram by default, got %q %v", s, err)
+	}
+}
+
+func TestSketchHistogram(t *testing.T) {
+	sketch, _ := NewSketch(0.01)
+	var values []float64
+	for i := 1; i <= 100; i++ {
+		sketch.Add(float64(i))
+		values = append(values, float64(i))
+	}
+	want, _ := HistogramOf(values, HistogramLinear, 4)
+	got, err := sketch.Histogram(HistogramLinear, 4)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for i := range want.Counts {
+		if math.Abs(float64(got.Counts[i])-float64(want.Counts[i])) > 2 {
+			t.Errorf("Expected counts close to %v, got %v", want.Counts, got.Counts)
+			break
+		}
+	}
+	if got.Total() != 100 {
+		t.Errorf("Expected 100 values, got %v", got.Total())
+	}
+}
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// Integral is the area under a series of samples ordered by time, computed with
+// the trapezoidal rule so irregular intervals and gaps are weighted by their length
+type Integral struct {
+	// Area is in the unit of the values times seconds, ie. CPU percent seconds
+	Area float64
+	// Duration is the number of seconds between the first and the last sample
+	Duration float64
+	samples  int
+	last     float64
+	previous float64
+}
+
+// Add adds a sample at a time in seconds, samples must be added in time order
+func (in *Integral) Add(seconds, value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil
+	}
+	if in.samples > 0 {
+		if seconds < in.last {
+			return fmt.Errorf("Sample at %v seconds is before the previous one at %v", seconds, in.last)
+		}
+		in.Area += (seconds - in.last) * (in.previous + value) / 2
+		in.Duration += seconds - in.last
+	}
+	in.samples++
+	in.last, in.previous = seconds, value
+	return nil
+}
+
+// Mean returns the time-weighted mean of the samples, which needs samples at two different times
+func (in Integral) Mean() (float64, error) {
+	if in.Duration == 0 {
+		return math.NaN(), fmt.Errorf("Invalid duration of 0 for %d samples", in.samples)
+	}
+	return in.Area / in.Duration, nil
+}
+
+// Integrate returns the integral of values sampled at times in seconds
+func Integrate(seconds, values []float64) (Integral, error) {
+	var in Integral
+	if len(seconds) != len(values) {
+		return in, fmt.Errorf("Got %d times for %d values", len(seconds), len(values))
+	}
+	for i := range values {
+		err := in.Add(seconds[i], values[i])
+		if err != nil {
+			return in, err
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIntegrate(t *testing.T) {
+	// A gap of 30s at 10 weighs three times the 10s samples at 40
+	in, err := Integrate([]float64{0, 10, 40, 50}, []float64{40, 10, 10, 40})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if in.Area != 250+300+250 || in.Duration != 50 {
+		t.Errorf("Expected an area of 800 over 50s, got %v over %v", in.Area, in.Duration)
+	}
+	if mean, _ := in.Mean(); mean != 16 {
+		t.Errorf("Expected a time-weighted mean of 16, got %v", mean)
+	}
+
+	// Uneven gaps between changing values tell the trapezoidal rule, 50+750,
+	// from weighing each sample by the time until the next one, 0+300, or since the previous one, 100+1200
+	in, _ = Integrate([]float64{0, 10, 40}, []float64{0, 10, 40})
+	if mean, _ := in.Mean(); in.Area != 800 || mean != 20 {
+		t.Errorf("Expected a trapezoidal area of 800 and mean of 20, got %v and %v", in.Area, mean)
+	}
+
+	in, _ = Integrate([]float64{0, 10, 20}, []float64{1, math.NaN(), 3})
+	if in.Area != 40 || in.Duration != 20 {
+		t.Errorf("Expected NaN to be bridged, got %v over %v", in.Area, in.Duration)
+	}
+
+	if _, err := Integrate([]float64{10, 0}, []float64{1, 2}); err == nil {
+		t.Errorf("Expected an error for samples out of order")
+	}
+	if _, err := Integrate([]float64{0}, []float64{1, 2}); err == nil {
+		t.Errorf("Expected an error for missing times")
+	}
+	in, _ = Integrate([]float64{5}, []float64{1})
+	if _, err := in.Mean(); err == nil {
+		t.Errorf("Expected an error for the mean of a single sample")
+	}
+}
+
+func TestSetIntegral(t *testing.T) {
+	s, _ := Summarize([]float64{1, 3}, Options{})
+	if !math.IsNaN(s.TimeWeightedMean) || !math.IsNaN(s.Integral) {
+		t.Errorf("Expected no integral without times, got %+v", s)
+	}
+	in, _ := Integrate([]float64{0, 2}, []float64{1, 3})
+	s.SetIntegral(in)
+	if s.TimeWeightedMean != 2 || s.Integral != 4 || s.Duration != 2 {
+		t.Errorf("Expected a time-weighted mean of 2 and an integral of 4 over 2s, got %+v", s)
+	}
+}
@@ -0,0 +1,48 @@
+package stats
+
+import "fmt"
+
+// PercentileMethod is a sample percentile definition, the Hyndman and Fan
+// (1996) types 1 to 9 are named like their numpy counterparts
+type PercentileMethod string
+
+// Percentile methods
+const (
+	// PercentileInvertedCDF is the nearest rank, type 1
+	PercentileInvertedCDF PercentileMethod = "inverted-cdf"
+	// PercentileAveragedInvertedCDF averages at discontinuities, type 2
+	PercentileAveragedInvertedCDF PercentileMethod = "averaged-inverted-cdf"
+	// PercentileClosestObservation is the nearest even rank, type 3 (SAS)
+	PercentileClosestObservation PercentileMethod = "closest-observation"
+	// PercentileInterpolatedInvertedCDF interpolates the empirical CDF, type 4
+	PercentileInterpolatedInvertedCDF PercentileMethod = "interpolated-inverted-cdf"
+	// PercentileHazen is type 5
+	PercentileHazen PercentileMethod = "hazen"
+	// PercentileWeibull is type 6, Excel PERCENTILE.EXC and Minitab
+	PercentileWeibull PercentileMethod = "weibull"
+	// PercentileLinear is type 7, the default of numpy, R and Excel
+	// PERCENTILE.INC, also used by Prometheus quantile_over_time
+	PercentileLinear PercentileMethod = "linear"
+	// PercentileMedianUnbiased is type 8, recommended by Hyndman and Fan
+	PercentileMedianUnbiased PercentileMethod = "median-unbiased"
+	// PercentileNormalUnbiased is type 9
+	PercentileNormalUnbiased PercentileMethod = "normal-unbiased"
+	// PercentileMidpoint averages the two ranks around the linear index
+	PercentileMidpoint PercentileMethod = "midpoint"
+)
+
+// ParsePercentileMethod validates a percentile method name, nearest-rank is
+// an alias of inverted-cdf and an empty name is linear
+func ParsePercentileMethod(method string) (PercentileMethod, error) {
+	switch m := PercentileMethod(method); m {
+	case PercentileInvertedCDF, PercentileAveragedInvertedCDF, PercentileClosestObservation,
+		PercentileInterpolatedInvertedCDF, PercentileHazen, PercentileWeibull, PercentileLinear,
+		PercentileMedianUnbiased, PercentileNormalUnbiased, PercentileMidpoint:
+		return m, nil
+	case "nearest-rank":
+		return PercentileInvertedCDF, nil
+	case "":
+		return PercentileLinear, nil
+	}
+	return "", fmt.Errorf("Unknown percentile method %q", method)
+}
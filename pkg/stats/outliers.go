@@ -0,0 +1,148 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// OutlierMethod decides the fences outside which samples are outliers
+type OutlierMethod string
+
+const (
+	// OutliersNone disables outlier detection
+	OutliersNone OutlierMethod = "none"
+	// OutliersIQR fences samples k interquartile ranges beyond the quartiles, Tukey's k is 1.5
+	OutliersIQR OutlierMethod = "iqr"
+	// OutliersMAD fences samples k scaled median absolute deviations from the median, commonly 3
+	OutliersMAD OutlierMethod = "mad"
+)
+
+// ParseOutlierMethod validates an outlier method name
+func ParseOutlierMethod(method string) (OutlierMethod, error) {
+	switch m := OutlierMethod(method); m {
+	case OutliersNone, OutliersIQR, OutliersMAD:
+		return m, nil
+	case "":
+		return OutliersNone, nil
+	}
+	return "", fmt.Errorf("Unknown outlier method %q", method)
+}
+
+// DefaultOutlierK returns the usual fence multiplier of a method
+func DefaultOutlierK(method OutlierMethod) float64 {
+	if method == OutliersMAD {
+		return 3
+	}
+	return 1.5
+}
+
+// madScale makes the median absolute deviation estimate the standard deviation of normal data
+const madScale = 1.4826
+
+// Fences returns the bounds outside which samples are outliers, the input is not modified
+func Fences(input []float64, method OutlierMethod, k float64) (low, high float64, err error) {
+	if len(input) == 0 {
+		return math.NaN(), math.NaN(), fmt.Errorf("Invalid float slice: %g", input)
+	}
+	sorted := sortedCopy(input)
+	switch method {
+	case OutliersIQR:
+		q1, _ := percentileSorted(sorted, 25, PercentileLinear)
+		q3, _ := percentileSorted(sorted, 75, PercentileLinear)
+		return q1 - k*(q3-q1), q3 + k*(q3-q1), nil
+	case OutliersMAD:
+		median, _ := percentileSorted(sorted, 50, PercentileLinear)
+		deviations := make([]float64, len(sorted))
+		for i, value := range sorted {
+			deviations[i] = math.Abs(value - median)
+		}
+		mad, _ := Median(deviations)
+		return median - k*madScale*mad, median + k*madScale*mad, nil
+	}
+	return math.NaN(), math.NaN(), fmt.Errorf("Unknown outlier method %q", method)
+}
+
+// Spike is a run of consecutive outliers, from the time of the first one to
+// the time of the next sample within the fences, in seconds
+type Spike struct {
+	Start, End float64
+	// Peak is the sample furthest outside the fences
+	Peak float64
+}
+
+// Spikes returns the runs of samples outside the fences of a series ordered by time
+func Spikes(seconds, values []float64, low, high float64) []Spike {
+	var spikes []Spike
+	var spike *Spike
+	for i := range values {
+		if math.IsNaN(values[i]) {
+			continue
+		}
+		outside := values[i] < low || values[i] > high
+		if spike != nil {
+			// The spike lasts until this sample
+			spike.End = seconds[i]
+			if !outside {
+				spike = nil
+				continue
+			}
+			if distance(values[i], low, high) > distance(spike.Peak, low, high) {
+				spike.Peak = values[i]
+			}
+			continue
+		}
+		if outside {
+			spikes = append(spikes, Spike{Start: seconds[i], End: seconds[i], Peak: values[i]})
+			spike = &spikes[len(spikes)-1]
+		}
+	}
+	return spikes
+}
+
+// distance returns how far a value is outside the fences
+func distance(value, low, high float64) float64 {
+	return math.Max(low-value, value-high)
+}
+
+// Threshold accumulates the time a series ordered by time spends above a
+// value, every sample holding until the next one
+type Threshold struct {
+	Value float64
+	// Seconds above the value out of Total seconds
+	Seconds, Total float64
+	last           float64
+	above          bool
+	samples        int
+}
+
+// Add adds a sample at a time in seconds
+func (th *Threshold) Add(seconds, value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+	if th.samples > 0 && seconds > th.last {
+		th.Total += seconds - th.last
+		if th.above {
+			th.Seconds += seconds - th.last
+		}
+	}
+	th.samples++
+	th.last, th.above = seconds, value > th.Value
+}
+
+// Fraction returns the fraction of the time spent above the value
+func (th Threshold) Fraction() float64 {
+	if th.Total == 0 {
+		return 0
+	}
+	return th.Seconds / th.Total
+}
+
+// TimeAbove returns the time values sampled at times in seconds spend above a threshold
+func TimeAbove(seconds, values []float64, threshold float64) Threshold {
+	th := Threshold{Value: threshold}
+	for i := range values {
+		th.Add(seconds[i], values[i])
+	}
+	return th
+}
@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFences(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9}
+	low, high, err := Fences(values, OutliersIQR, 1.5)
+	if err != nil || low != -3 || high != 13 {
+		t.Errorf("Expected IQR fences of -3 and 13, got %v and %v (%v)", low, high, err)
+	}
+	low, high, err = Fences(values, OutliersMAD, 3)
+	if err != nil || math.Abs(low-(5-3*madScale*2)) > 1e-9 || math.Abs(high-(5+3*madScale*2)) > 1e-9 {
+		t.Errorf("Expected MAD fences around 5, got %v and %v (%v)", low, high, err)
+	}
+	if _, _, err := Fences(nil, OutliersIQR, 1.5); err == nil {
+		t.Errorf("Expected an error for no values")
+	}
+	if m, err := ParseOutlierMethod(""); err != nil || m != OutliersNone {
+		t.Errorf("Expected no outlier detection by default, got %q %v", m, err)
+	}
+}
+
+func TestSpikes(t *testing.T) {
+	times := []float64{0, 10, 20, 30, 40, 50, 60, 70}
+	values := []float64{5, 5, 50, 80, 5, 5, 60, 5}
+	spikes := Spikes(times, values, 0, 10)
+	if len(spikes) != 2 {
+		t.Fatalf("Expected 2 spikes, got %v", spikes)
+	}
+	if spikes[0] != (Spike{Start: 20, End: 40, Peak: 80}) || spikes[1] != (Spike{Start: 60, End: 70, Peak: 60}) {
+		t.Errorf("Unexpected spikes %v", spikes)
+	}
+
+	// A spike at the end lasts until the last sample
+	spikes = Spikes([]float64{0, 10, 20}, []float64{5, 50, 60}, 0, 10)
+	if len(spikes) != 1 || spikes[0].End != 20 || spikes[0].Peak != 60 {
+		t.Errorf("Unexpected spikes %v", spikes)
+	}
+}
+
+func TestTimeAbove(t *testing.T) {
+	th := TimeAbove([]float64{0, 10, 40, 50}, []float64{90, 10, 95, 10}, 80)
+	if th.Seconds != 20 || th.Total != 50 || th.Fraction() != 0.4 {
+		t.Errorf("Expected 20 of 50 seconds above 80, got %+v", th)
+	}
+	if (Threshold{}).Fraction() != 0 {
+		t.Errorf("Expected no time above without samples")
+	}
+}
@@ -0,0 +1,245 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// minIndexable is the smallest magnitude kept in a bucket, smaller values count as zero
+const minIndexable = 1e-9
+
+// Sketch is a DDSketch: values are counted in buckets growing geometrically by
+// gamma, so every percentile is within RelativeAccuracy of an exact value of
+// the input. Its size grows with the logarithm of the range of the values, not
+// with their number, and sketches of the same accuracy merge exactly.
+type Sketch struct {
+	RelativeAccuracy float64
+	Count            uint64
+	// Zero counts the values closer to 0 than minIndexable
+	Zero          uint64
+	Min, Max, Sum float64
+	// Positive and Negative count the values by bucket index, negative values by their magnitude
+	Positive map[int]uint64 `json:",omitempty"`
+	Negative map[int]uint64 `json:",omitempty"`
+}
+
+// NewSketch returns an empty sketch with a relative accuracy between 0 and 1, ie. 0.01 for 1%
+func NewSketch(relativeAccuracy float64) (*Sketch, error) {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		return nil, fmt.Errorf("Invalid relative accuracy %v, expected a number between 0 and 1", relativeAccuracy)
+	}
+	return &Sketch{
+		RelativeAccuracy: relativeAccuracy,
+		Positive:         map[int]uint64{},
+		Negative:         map[int]uint64{},
+	}, nil
+}
+
+func (s *Sketch) gamma() float64 {
+	return (1 + s.RelativeAccuracy) / (1 - s.RelativeAccuracy)
+}
+
+// index returns the bucket of a positive value
+func (s *Sketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) / math.Log(s.gamma())))
+}
+
+// value returns the value of a bucket, within the relative accuracy of all of its values
+func (s *Sketch) value(index int) float64 {
+	gamma := s.gamma()
+	return 2 * math.Pow(gamma, float64(index)) / (gamma + 1)
+}
+
+// Add counts a value in the sketch, NaN and infinite values are ignored
+func (s *Sketch) Add(value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	if s.Count == 0 || value < s.Min {
+		s.Min = value
+	}
+	if s.Count == 0 || value > s.Max {
+		s.Max = value
+	}
+	s.Count++
+	s.Sum += value
+
+	switch {
+	case math.Abs(value) < minIndexable:
+		s.Zero++
+	case value > 0:
+		if s.Positive == nil {
+			s.Positive = map[int]uint64{}
+		}
+		s.Positive[s.index(value)]++
+	default:
+		if s.Negative == nil {
+			s.Negative = map[int]uint64{}
+		}
+		s.Negative[s.index(-value)]++
+	}
+}
+
+// Merge adds the values of another sketch of the same accuracy, the result is
+// the sketch of both inputs
+func (s *Sketch) Merge(o *Sketch) error {
+	if o == nil || o.Count == 0 {
+		return nil
+	}
+	if o.RelativeAccuracy != s.RelativeAccuracy {
+		return fmt.Errorf("Cannot merge sketches with relative accuracy %v and %v", s.RelativeAccuracy, o.RelativeAccuracy)
+	}
+	if s.Count == 0 || o.Min < s.Min {
+		s.Min = o.Min
+	}
+	if s.Count == 0 || o.Max > s.Max {
+		s.Max = o.Max
+	}
+	s.Count += o.Count
+	s.Zero += o.Zero
+	s.Sum += o.Sum
+	if s.Positive == nil {
+		s.Positive = map[int]uint64{}
+	}
+	for i, n := range o.Positive {
+		s.Positive[i] += n
+	}
+	if s.Negative == nil {
+		s.Negative = map[int]uint64{}
+	}
+	for i, n := range o.Negative {
+		s.Negative[i] += n
+	}
+	return nil
+}
+
+// Mean returns the exact arithmetic mean of the values in the sketch
+func (s *Sketch) Mean() (float64, error) {
+	if s.Count == 0 {
+		return math.NaN(), fmt.Errorf("Empty sketch")
+	}
+	return s.Sum / float64(s.Count), nil
+}
+
+// Percentile returns the k-th percentile of the values in the sketch,
+// interpolating between the closest ranks like the linear method
+func (s *Sketch) Percentile(percent float64) (float64, error) {
+	if s.Count == 0 {
+		return math.NaN(), fmt.Errorf("Empty sketch")
+	}
+	if percent < 0 || percent > 100 {
+		return math.NaN(), fmt.Errorf("Invalid percentile: %v", percent)
+	}
+
+	rank := percent / 100 * float64(s.Count-1)
+	lower := s.valueAt(math.Floor(rank))
+	f := rank - math.Floor(rank)
+	if f == 0 {
+		return lower, nil
+	}
+	return (1-f)*lower + f*s.valueAt(math.Floor(rank)+1), nil
+}
+
+// valueAt returns the estimated value of the 0-based rank
+func (s *Sketch) valueAt(rank float64) float64 {
+	var seen float64
+	// The largest negative magnitudes are the lowest values
+	for _, i := range sortedIndexes(s.Negative, true) {
+		seen += float64(s.Negative[i])
+		if seen > rank {
+			return s.clamp(-s.value(i))
+		}
+	}
+	seen += float64(s.Zero)
+	if seen > rank {
+		return s.clamp(0)
+	}
+	for _, i := range sortedIndexes(s.Positive, false) {
+		seen += float64(s.Positive[i])
+		if seen > rank {
+			return s.clamp(s.value(i))
+		}
+	}
+	return s.Max
+}
+
+// clamp keeps an estimate within the exact minimum and maximum
+func (s *Sketch) clamp(value float64) float64 {
+	return math.Max(s.Min, math.Min(s.Max, value))
+}
+
+func sortedIndexes(buckets map[int]uint64, descending bool) []int {
+	indexes := make([]int, 0, len(buckets))
+	for i := range buckets {
+		indexes = append(indexes, i)
+	}
+	if descending {
+		sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+	} else {
+		sort.Ints(indexes)
+	}
+	return indexes
+}
+
+// Stream summarizes values as they are added without keeping them, percentiles
+// come from a Sketch and the mean and variance are computed exactly
+type Stream struct {
+	sketch   *Sketch
+	mean, m2 float64
+}
+
+// NewStream returns an empty stream with a sketch of the given relative accuracy
+func NewStream(relativeAccuracy float64) (*Stream, error) {
+	sketch, err := NewSketch(relativeAccuracy)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{sketch: sketch}, nil
+}
+
+// Add adds a value to the stream, NaN and infinite values are ignored
+func (s *Stream) Add(value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	s.sketch.Add(value)
+	// Welford's online variance
+	delta := value - s.mean
+	s.mean += delta / float64(s.sketch.Count)
+	s.m2 += delta * (value - s.mean)
+}
+
+// Sketch returns the sketch of the values added to the stream
+func (s *Stream) Sketch() *Sketch {
+	return s.sketch
+}
+
+// Summarize returns the statistics of the values added to the stream, the
+// percentiles are estimated by the sketch whatever the method of opts
+func (s *Stream) Summarize(opts Options) (Summary, error) {
+	summary := emptySummary(int(s.sketch.Count), opts)
+	if s.sketch.Count == 0 {
+		return summary, fmt.Errorf("Empty stream")
+	}
+
+	summary.Min, summary.Max, summary.Mean = s.sketch.Min, s.sketch.Max, s.mean
+	summary.Median, _ = s.sketch.Percentile(50)
+	summary.Pct95, _ = s.sketch.Percentile(95)
+	for _, percent := range opts.Percentiles {
+		value, err := s.sketch.Percentile(percent)
+		if err != nil {
+			return summary, err
+		}
+		summary.Percentiles[percent] = value
+	}
+
+	if s.sketch.Count > 1 {
+		summary.Variance = s.m2 / float64(s.sketch.Count-1)
+		summary.StdDev = math.Sqrt(summary.Variance)
+		if summary.Mean != 0 {
+			summary.CV = summary.StdDev / math.Abs(summary.Mean)
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,129 @@
+package stats
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSketchPercentile(t *testing.T) {
+	sketch, _ := NewSketch(0.01)
+	var values []float64
+	for i := 1; i <= 10000; i++ {
+		v := float64(i) * 0.37
+		values = append(values, v)
+		sketch.Add(v)
+	}
+	for _, percent := range []float64{0, 1, 25, 50, 90, 95, 99, 99.9, 100} {
+		exact, _ := Percentile(values, percent)
+		got, err := sketch.Percentile(percent)
+		if err != nil {
+			t.Errorf("p%v: unexpected error %v", percent, err)
+		}
+		if math.Abs(got-exact) > 0.01*exact {
+			t.Errorf("p%v: expected about %v instead we got %v", percent, exact, got)
+		}
+	}
+	if sketch.Count != 10000 || sketch.Min != 0.37 || sketch.Max != 3700 {
+		t.Errorf("Expected 10000 values from 0.37 to 3700, got %d from %v to %v", sketch.Count, sketch.Min, sketch.Max)
+	}
+}
+
+func TestSketchNegativeAndZero(t *testing.T) {
+	sketch, _ := NewSketch(0.02)
+	for _, v := range []float64{-100, -10, 0, 0, 10, 100, math.NaN()} {
+		sketch.Add(v)
+	}
+	if p, _ := sketch.Percentile(0); p != -100 {
+		t.Errorf("Expected a minimum of -100, got %v", p)
+	}
+	if p, _ := sketch.Percentile(50); p != 0 {
+		t.Errorf("Expected a median of 0, got %v", p)
+	}
+	if p, _ := sketch.Percentile(20); math.Abs(p+10) > 0.2 {
+		t.Errorf("Expected about -10, got %v", p)
+	}
+	if sketch.Count != 6 {
+		t.Errorf("Expected NaN to be ignored, got %d values", sketch.Count)
+	}
+	if _, err := NewSketch(1); err == nil {
+		t.Errorf("Expected an error for an accuracy of 1")
+	}
+	empty, _ := NewSketch(0.01)
+	if _, err := empty.Percentile(50); err == nil {
+		t.Errorf("Expected an error for an empty sketch")
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	a, _ := NewSketch(0.01)
+	b, _ := NewSketch(0.01)
+	all, _ := NewSketch(0.01)
+	for i := 0; i < 1000; i++ {
+		v := math.Exp(float64(i%97) / 10)
+		if i%3 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(-v)
+		}
+		if i%3 == 0 {
+			all.Add(v)
+		} else {
+			all.Add(-v)
+		}
+	}
+	err := a.Merge(b)
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(a.Positive, all.Positive) || !reflect.DeepEqual(a.Negative, all.Negative) ||
+		a.Count != all.Count || a.Min != all.Min || a.Max != all.Max {
+		t.Errorf("Merged sketch differs from the sketch of all values")
+	}
+
+	other, _ := NewSketch(0.05)
+	other.Add(1)
+	if err := a.Merge(other); err == nil {
+		t.Errorf("Expected an error merging sketches of different accuracy")
+	}
+}
+
+func TestSketchJSON(t *testing.T) {
+	sketch, _ := NewSketch(0.01)
+	for _, v := range []float64{-3, 0, 1.5, 42, 1e6} {
+		sketch.Add(v)
+	}
+	b, err := json.Marshal(sketch)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var out Sketch
+	err = json.Unmarshal(b, &out)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(*sketch, out) {
+		t.Errorf("Expected %+v after a JSON round trip, got %+v", *sketch, out)
+	}
+}
+
+func TestStreamSummarize(t *testing.T) {
+	for _, v := range tests {
+		stream, _ := NewStream(0.01)
+		for _, value := range v.values {
+			stream.Add(value)
+		}
+		s, err := stream.Summarize(Options{Percentiles: []float64{50}})
+		if err != nil {
+			t.Errorf("For %v, unexpected error %v", v.values, err)
+		}
+		if s.Count != len(v.values) || s.Min != v.min || s.Max != v.max || math.Abs(s.Mean-v.mean) > 1e-9 ||
+			math.Abs(s.Variance-v.variance) > 1e-9 {
+			t.Errorf("For %v, got %+v", v.values, s)
+		}
+		if math.Abs(s.Median-v.median) > 0.01*v.median || s.Percentiles[50] != s.Median {
+			t.Errorf("For %v, expected a median of about %v, got %v", v.values, v.median, s.Median)
+		}
+	}
+}
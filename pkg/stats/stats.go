@@ -52,26 +52,258 @@ func Maximum(input []float64) (max float64, err error) {
 	return max, nil
 }
 
-// Percentile returns the k-th percentile of values in a range of numbers
+// Percentile returns the k-th percentile of values in a range of numbers using
+// linear interpolation, the input is not modified
 func Percentile(input []float64, percent float64) (percentile float64, err error) {
+	return PercentileWith(input, percent, PercentileLinear)
+}
+
+// PercentileWith returns the k-th percentile of values in a range of numbers
+// estimated with the given method, the input is not modified
+func PercentileWith(input []float64, percent float64, method PercentileMethod) (percentile float64, err error) {
 	if len(input) == 0 {
 		return math.NaN(), fmt.Errorf("Invalid float slice: %g", input)
 	}
 
-	sort.Float64s(input)
-	index := (percent / 100) * float64(len(input)-1)
+	return percentileSorted(sortedCopy(input), percent, method)
+}
+
+// sortedCopy returns a sorted copy of a slice of float64 numbers
+func sortedCopy(input []float64) []float64 {
+	sorted := make([]float64, len(input))
+	copy(sorted, input)
+	sort.Float64s(sorted)
+	return sorted
+}
+
+// percentileSorted returns the k-th percentile of an already sorted slice
+func percentileSorted(sorted []float64, percent float64, method PercentileMethod) (percentile float64, err error) {
+	if len(sorted) == 0 {
+		return math.NaN(), fmt.Errorf("Invalid float slice: %g", sorted)
+	}
+	if percent < 0 || percent > 100 {
+		return math.NaN(), fmt.Errorf("Invalid percentile: %v", percent)
+	}
+
+	switch method {
+	case PercentileLinear, "":
+		return linear(sorted, percent)
+	case PercentileMidpoint:
+		index := (percent / 100) * float64(len(sorted)-1)
+		return (sorted[int(math.Floor(index))] + sorted[int(math.Ceil(index))]) / 2, nil
+	}
+	return hyndmanFan(sorted, percent/100, method)
+}
+
+// linear interpolates between the closest ranks, Hyndman and Fan type 7
+func linear(sorted []float64, percent float64) (percentile float64, err error) {
+	index := (percent / 100) * float64(len(sorted)-1)
 	// If index happens to be a round number
 	if index == float64(int64(index)) {
 		i := int(index)
-		return input[i], nil
+		return sorted[i], nil
 	}
 
 	// Otherwise interpolate percentile value
 	k := math.Floor(index)
 	f := index - k
-	if int(k) >= len(input) {
-		return math.NaN(), fmt.Errorf("Invalid index: %v/%v", k+1, len(input))
+	if int(k)+1 >= len(sorted) {
+		return math.NaN(), fmt.Errorf("Invalid index: %v/%v", k+1, len(sorted))
 	}
-	percentile = ((1 - f) * input[int(k)]) + (f * input[int(k)+1])
+	percentile = ((1 - f) * sorted[int(k)]) + (f * sorted[int(k)+1])
 	return percentile, nil
 }
+
+// hyndmanFan estimates the p-th quantile as (1-g)*x[j] + g*x[j+1] of the
+// 1-based order statistics, where j and g come from n*p+m of the method
+func hyndmanFan(sorted []float64, p float64, method PercentileMethod) (float64, error) {
+	n := float64(len(sorted))
+	var m float64
+	switch method {
+	case PercentileInvertedCDF, PercentileAveragedInvertedCDF, PercentileInterpolatedInvertedCDF:
+		m = 0
+	case PercentileClosestObservation:
+		m = -0.5
+	case PercentileHazen:
+		m = 0.5
+	case PercentileWeibull:
+		m = p
+	case PercentileMedianUnbiased:
+		m = (p + 1) / 3
+	case PercentileNormalUnbiased:
+		m = p/4 + 3.0/8
+	default:
+		return math.NaN(), fmt.Errorf("Unknown percentile method %q", method)
+	}
+
+	j := math.Floor(n*p + m)
+	g := n*p + m - j
+	switch method {
+	case PercentileInvertedCDF:
+		g = step(g > 0, 1, 0)
+	case PercentileAveragedInvertedCDF:
+		g = step(g > 0, 1, 0.5)
+	case PercentileClosestObservation:
+		g = step(g > 0 || math.Mod(j, 2) == 1, 1, 0)
+	}
+
+	// x[0] and x[n+1] are taken as x[1] and x[n]
+	at := func(i float64) float64 {
+		return sorted[int(math.Max(0, math.Min(n-1, i-1)))]
+	}
+	if g == 0 {
+		return at(j), nil
+	}
+	return (1-g)*at(j) + g*at(j+1), nil
+}
+
+func step(cond bool, a, b float64) float64 {
+	if cond {
+		return a
+	}
+	return b
+}
+
+// Variance returns the sample variance of a slice of float64 numbers
+func Variance(input []float64) (float64, error) {
+	if len(input) < 2 {
+		return math.NaN(), fmt.Errorf("Invalid float slice, need at least 2 values: %g", input)
+	}
+
+	mean, _ := Mean(input)
+	var squares float64
+	for _, value := range input {
+		squares += (value - mean) * (value - mean)
+	}
+	return squares / float64(len(input)-1), nil
+}
+
+// StdDev returns the sample standard deviation of a slice of float64 numbers
+func StdDev(input []float64) (float64, error) {
+	variance, err := Variance(input)
+	if err != nil {
+		return math.NaN(), err
+	}
+	return math.Sqrt(variance), nil
+}
+
+// CoefficientOfVariation returns the standard deviation relative to the mean
+func CoefficientOfVariation(input []float64) (float64, error) {
+	stddev, err := StdDev(input)
+	if err != nil {
+		return math.NaN(), err
+	}
+	mean, _ := Mean(input)
+	if mean == 0 {
+		return math.NaN(), fmt.Errorf("Invalid mean of 0 for coefficient of variation")
+	}
+	return stddev / math.Abs(mean), nil
+}
+
+// Median returns the middle value of a slice of float64 numbers, the input is not modified
+func Median(input []float64) (float64, error) {
+	return Percentile(input, 50)
+}
+
+// Options selects the optional statistics computed by Summarize
+type Options struct {
+	// Percentiles to compute besides the median and the 95th, between 0 and 100
+	Percentiles []float64
+	// Method estimates every percentile including the median, defaults to PercentileLinear
+	Method PercentileMethod
+}
+
+// Summary holds the statistics of a slice of float64 numbers. Statistics that
+// cannot be computed, like the variance of a single value, are NaN.
+type Summary struct {
+	Count                         int
+	Min, Max, Mean, Median, Pct95 float64
+	Variance, StdDev, CV          float64
+	Percentiles                   map[float64]float64
+	// TimeWeightedMean, Integral and Duration come from SetIntegral, the
+	// integral is in the unit of the values times seconds
+	TimeWeightedMean, Integral, Duration float64
+	// SlopePerHour, R2 and TrendEnd come from SetTrend
+	SlopePerHour, R2, TrendEnd float64
+}
+
+// emptySummary returns a summary of count values with NaN statistics
+func emptySummary(count int, opts Options) Summary {
+	s := Summary{
+		Count:       count,
+		Min:         math.NaN(),
+		Max:         math.NaN(),
+		Mean:        math.NaN(),
+		Median:      math.NaN(),
+		Pct95:       math.NaN(),
+		Variance:    math.NaN(),
+		StdDev:      math.NaN(),
+		CV:          math.NaN(),
+		Percentiles: map[float64]float64{},
+
+		TimeWeightedMean: math.NaN(),
+		Integral:         math.NaN(),
+		Duration:         math.NaN(),
+		SlopePerHour:     math.NaN(),
+		R2:               math.NaN(),
+		TrendEnd:         math.NaN(),
+	}
+	for _, percent := range opts.Percentiles {
+		s.Percentiles[percent] = math.NaN()
+	}
+	return s
+}
+
+// Summarize computes every statistic of a slice of float64 numbers in a single
+// pass over a sorted copy, the input is not modified
+func Summarize(input []float64, opts Options) (Summary, error) {
+	s := emptySummary(len(input), opts)
+	if len(input) == 0 {
+		return s, fmt.Errorf("Invalid float slice: %g", input)
+	}
+
+	sorted := sortedCopy(input)
+	s.Min = sorted[0]
+	s.Max = sorted[len(sorted)-1]
+	s.Mean = sum(sorted) / float64(len(sorted))
+	var err error
+	s.Median, err = percentileSorted(sorted, 50, opts.Method)
+	if err != nil {
+		return s, err
+	}
+	s.Pct95, _ = percentileSorted(sorted, 95, opts.Method)
+	for _, percent := range opts.Percentiles {
+		value, err := percentileSorted(sorted, percent, opts.Method)
+		if err != nil {
+			return s, err
+		}
+		s.Percentiles[percent] = value
+	}
+
+	if len(sorted) > 1 {
+		var squares float64
+		for _, value := range sorted {
+			squares += (value - s.Mean) * (value - s.Mean)
+		}
+		s.Variance = squares / float64(len(sorted)-1)
+		s.StdDev = math.Sqrt(s.Variance)
+		if s.Mean != 0 {
+			s.CV = s.StdDev / math.Abs(s.Mean)
+		}
+	}
+	return s, nil
+}
+
+// SetIntegral adds the time-weighted mean and the integral of the values to a summary
+func (s *Summary) SetIntegral(in Integral) {
+	if in.samples == 0 {
+		return
+	}
+	s.Integral, s.Duration = in.Area, in.Duration
+	s.TimeWeightedMean, _ = in.Mean()
+}
+
+// SetTrend adds the trend of the values over time to a summary
+func (s *Summary) SetTrend(t Trend) {
+	s.SlopePerHour, s.R2, s.TrendEnd = t.SlopePerHour, t.R2, t.End
+}
@@ -1,19 +1,25 @@
 package stats
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 type teststruct struct {
-	values []float64
-	sum    float64
-	mean   float64
-	min    float64
-	max    float64
-	p95    float64
+	values   []float64
+	sum      float64
+	mean     float64
+	min      float64
+	max      float64
+	p95      float64
+	variance float64
+	median   float64
 }
 
 var tests = []teststruct{
 	// Floating precision error result kept intact for percentile result, rounding done in print, not in Percentile function
-	{[]float64{1, 3, 5, 7, 9, 11, 13, 15, 17, 19, 21, 23, 25, 27, 29, 31, 33, 35, 37, 39, 41, 43, 45, 47, 49}, 625, 25, 1, 49, 46.599999999999994},
+	{[]float64{1, 3, 5, 7, 9, 11, 13, 15, 17, 19, 21, 23, 25, 27, 29, 31, 33, 35, 37, 39, 41, 43, 45, 47, 49}, 625, 25, 1, 49, 46.599999999999994, 216.66666666666666, 25},
+	{[]float64{4, 1, 3, 2}, 10, 2.5, 1, 4, 3.8499999999999996, 1.6666666666666667, 2.5},
 }
 
 func TestSum(t *testing.T) {
@@ -60,3 +66,158 @@ func TestPercentile95(t *testing.T) {
 		}
 	}
 }
+
+func TestVariance(t *testing.T) {
+	for _, v := range tests {
+		variance, _ := Variance(v.values)
+		if variance != v.variance {
+			t.Errorf("For %v, expected %v instead we got %v", v.values, v.variance, variance)
+		}
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	for _, v := range tests {
+		stddev, _ := StdDev(v.values)
+		if stddev != math.Sqrt(v.variance) {
+			t.Errorf("For %v, expected %v instead we got %v", v.values, math.Sqrt(v.variance), stddev)
+		}
+	}
+}
+
+func TestCoefficientOfVariation(t *testing.T) {
+	for _, v := range tests {
+		cv, _ := CoefficientOfVariation(v.values)
+		if cv != math.Sqrt(v.variance)/v.mean {
+			t.Errorf("For %v, expected %v instead we got %v", v.values, math.Sqrt(v.variance)/v.mean, cv)
+		}
+	}
+	if _, err := CoefficientOfVariation([]float64{-1, 1}); err == nil {
+		t.Errorf("Expected an error for a mean of 0")
+	}
+}
+
+func TestMedian(t *testing.T) {
+	for _, v := range tests {
+		input := append([]float64{}, v.values...)
+		median, _ := Median(input)
+		if median != v.median {
+			t.Errorf("For %v, expected %v instead we got %v", v.values, v.median, median)
+		}
+		for i := range input {
+			if input[i] != v.values[i] {
+				t.Errorf("For %v, Median modified its input to %v", v.values, input)
+				break
+			}
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	for _, v := range tests {
+		input := append([]float64{}, v.values...)
+		s, err := Summarize(input, Options{Percentiles: []float64{50, 95}})
+		if err != nil {
+			t.Errorf("For %v, unexpected error %v", v.values, err)
+		}
+		if s.Count != len(v.values) || s.Min != v.min || s.Max != v.max || s.Mean != v.mean ||
+			s.Median != v.median || s.Pct95 != v.p95 || s.Variance != v.variance || s.StdDev != math.Sqrt(v.variance) {
+			t.Errorf("For %v, got %+v", v.values, s)
+		}
+		if s.Percentiles[50] != v.median || s.Percentiles[95] != v.p95 {
+			t.Errorf("For %v, got percentiles %v", v.values, s.Percentiles)
+		}
+		for i := range input {
+			if input[i] != v.values[i] {
+				t.Errorf("For %v, Summarize modified its input to %v", v.values, input)
+				break
+			}
+		}
+	}
+}
+
+func TestSummarizeFewValues(t *testing.T) {
+	s, err := Summarize(nil, Options{Percentiles: []float64{99}})
+	if err == nil {
+		t.Errorf("Expected an error for no values")
+	}
+	if s.Count != 0 || !math.IsNaN(s.Mean) || !math.IsNaN(s.Percentiles[99]) {
+		t.Errorf("Expected NaN statistics for no values, got %+v", s)
+	}
+
+	s, err = Summarize([]float64{3}, Options{})
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if s.Min != 3 || s.Max != 3 || s.Pct95 != 3 || !math.IsNaN(s.Variance) || !math.IsNaN(s.CV) {
+		t.Errorf("Expected the statistics of a single value, got %+v", s)
+	}
+
+	if _, err := Summarize([]float64{1, 2}, Options{Percentiles: []float64{101}}); err == nil {
+		t.Errorf("Expected an error for a percentile above 100")
+	}
+}
+
+func TestPercentileKeepsInput(t *testing.T) {
+	input := []float64{4, 1, 3, 2}
+	Percentile(input, 95)
+	if input[0] != 4 || input[1] != 1 || input[2] != 3 || input[3] != 2 {
+		t.Errorf("Percentile modified its input to %v", input)
+	}
+}
+
+// Reference outputs of R quantile(x, p, type = 1:9), the Wikipedia Percentile
+// examples, Excel PERCENTILE.EXC and numpy percentile(method="midpoint")
+var methodTests = []struct {
+	values  []float64
+	percent float64
+	method  PercentileMethod
+	want    float64
+}{
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileInvertedCDF, 1},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileAveragedInvertedCDF, 1.5},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileClosestObservation, 1},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileInterpolatedInvertedCDF, 1},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileHazen, 1.5},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileWeibull, 1.1},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileLinear, 1.9},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileMedianUnbiased, 1.3666666666666667},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 10, PercentileNormalUnbiased, 1.4},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 50, PercentileInvertedCDF, 5},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 50, PercentileAveragedInvertedCDF, 5.5},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 50, PercentileClosestObservation, 5},
+	{[]float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 100, PercentileNormalUnbiased, 10},
+	{[]float64{15, 20, 35, 40, 50}, 40, PercentileInvertedCDF, 20},
+	{[]float64{15, 20, 35, 40, 50}, 40, PercentileHazen, 27.5},
+	{[]float64{15, 20, 35, 40, 50}, 40, PercentileWeibull, 26},
+	{[]float64{15, 20, 35, 40, 50}, 40, PercentileLinear, 29},
+	{[]float64{15, 20, 35, 40, 50}, 40, PercentileMidpoint, 27.5},
+	{[]float64{15, 20, 35, 40, 50}, 0, PercentileWeibull, 15},
+	{[]float64{1, 2, 3, 6, 6, 6, 7, 8, 9}, 25, PercentileWeibull, 2.5},
+	{[]float64{1, 2, 3, 6, 6, 6, 7, 8, 9}, 25, PercentileLinear, 3},
+	{[]float64{4, 1, 3, 2}, 50, PercentileMidpoint, 2.5},
+}
+
+func TestPercentileWith(t *testing.T) {
+	for _, v := range methodTests {
+		got, err := PercentileWith(v.values, v.percent, v.method)
+		if err != nil {
+			t.Errorf("For %v p%v %s, unexpected error %v", v.values, v.percent, v.method, err)
+		}
+		if math.Abs(got-v.want) > 1e-9 {
+			t.Errorf("For %v p%v %s, expected %v instead we got %v", v.values, v.percent, v.method, v.want, got)
+		}
+	}
+}
+
+func TestParsePercentileMethod(t *testing.T) {
+	if m, err := ParsePercentileMethod("nearest-rank"); err != nil || m != PercentileInvertedCDF {
+		t.Errorf("Expected nearest-rank to be inverted-cdf, got %q %v", m, err)
+	}
+	if m, err := ParsePercentileMethod(""); err != nil || m != PercentileLinear {
+		t.Errorf("Expected the default to be linear, got %q %v", m, err)
+	}
+	if _, err := ParsePercentileMethod("excel"); err == nil {
+		t.Errorf("Expected an error for an unknown method")
+	}
+}
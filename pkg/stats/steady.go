@@ -0,0 +1,72 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// SteadyState finds the steady part of values sampled at times in seconds, the
+// longest run of consecutive sliding windows of length seconds whose coefficient
+// of variation is at most maxCV. Warm-up and cool-down samples fall outside of
+// it, as do idle plateaus before or after a longer load.
+func SteadyState(seconds, values []float64, length, maxCV float64) (start, end float64, err error) {
+	if len(seconds) != len(values) {
+		return math.NaN(), math.NaN(), fmt.Errorf("Got %d times for %d values", len(seconds), len(values))
+	}
+	if length <= 0 || maxCV <= 0 {
+		return math.NaN(), math.NaN(), fmt.Errorf("Invalid steady state window %vs with a CV of %v, expected positive numbers", length, maxCV)
+	}
+	var times, samples []float64
+	for i := range values {
+		if !math.IsNaN(values[i]) && !math.IsInf(values[i], 0) {
+			times = append(times, seconds[i])
+			samples = append(samples, values[i])
+		}
+	}
+	n := len(samples)
+	if n < 2 || times[n-1]-times[0] < length {
+		return math.NaN(), math.NaN(), fmt.Errorf("Samples span less than the %vs steady state window", length)
+	}
+
+	sums := make([]float64, n+1)
+	squares := make([]float64, n+1)
+	for i, x := range samples {
+		sums[i+1] = sums[i] + x
+		squares[i+1] = squares[i] + x*x
+	}
+
+	start, end = math.NaN(), math.NaN()
+	// runStart is the start of the current run of consecutive steady windows
+	runStart := math.NaN()
+	// The window of samples i to j-1 covers length seconds from times[i]
+	j := 0
+	for i := 0; i < n && times[i]+length <= times[n-1]; i++ {
+		for j < n && times[j] <= times[i]+length {
+			j++
+		}
+		steady := false
+		if j-i >= 2 {
+			count := float64(j - i)
+			mean := (sums[j] - sums[i]) / count
+			variance := math.Max(0, (squares[j]-squares[i]-count*mean*mean)/(count-1))
+			steady = variance == 0
+			if mean != 0 {
+				steady = math.Sqrt(variance)/math.Abs(mean) <= maxCV
+			}
+		}
+		if !steady {
+			runStart = math.NaN()
+			continue
+		}
+		if math.IsNaN(runStart) {
+			runStart = times[i]
+		}
+		if math.IsNaN(start) || times[j-1]-runStart > end-start {
+			start, end = runStart, times[j-1]
+		}
+	}
+	if math.IsNaN(start) {
+		return start, end, fmt.Errorf("No %vs window with a CV of at most %v", length, maxCV)
+	}
+	return start, end, nil
+}
@@ -0,0 +1,59 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSteadyState(t *testing.T) {
+	// A ramp up to 100 during the first 60s, a steady 100 and a drop after 300s
+	var times, values []float64
+	for i := 0; i <= 360; i += 10 {
+		value := 100 + math.Sin(float64(i))
+		switch {
+		case i < 60:
+			value = float64(i) * 100 / 60
+		case i > 300:
+			value = 5
+		}
+		times = append(times, float64(i))
+		values = append(values, value)
+	}
+	start, end, err := SteadyState(times, values, 60, 0.05)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if start != 60 || end != 300 {
+		t.Errorf("Expected a steady state from 60s to 300s, got %vs to %vs", start, end)
+	}
+
+	if _, _, err := SteadyState(times, values, 600, 0.05); err == nil {
+		t.Errorf("Expected an error for a window longer than the samples")
+	}
+	if _, _, err := SteadyState(times[:7], values[:7], 30, 0.05); err == nil {
+		t.Errorf("Expected an error for a ramp without steady state")
+	}
+
+	// Idle at 5 until 60s, a ramp to 100 until 120s, a load of 100 until 360s and idle again
+	var idleTimes, idleValues []float64
+	for i := 0; i <= 480; i += 10 {
+		value := 100 + math.Sin(float64(i))
+		switch {
+		case i < 60:
+			value = 5
+		case i < 120:
+			value = 5 + float64(i-60)*95/60
+		case i > 360:
+			value = 5
+		}
+		idleTimes = append(idleTimes, float64(i))
+		idleValues = append(idleValues, value)
+	}
+	start, end, err = SteadyState(idleTimes, idleValues, 60, 0.05)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if start != 120 || end != 360 {
+		t.Errorf("Expected a steady load from 120s to 360s, got %vs to %vs", start, end)
+	}
+}
@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxTrendSamples bounds the number of pairs of the Theil-Sen estimator,
+// longer series are thinned evenly
+const maxTrendSamples = 1000
+
+// Trend is a robust linear fit of values over time
+type Trend struct {
+	// SlopePerHour is in the unit of the values per hour
+	SlopePerHour float64
+	// R2 is the coefficient of determination of the fit, it is negative when
+	// the line fits worse than the mean and NaN for a constant series
+	R2 float64
+	// End is the fitted value at the last sample, where the series is heading
+	End float64
+}
+
+// TheilSen fits a line through values sampled at times in seconds, the slope is
+// the median of the slopes between every pair of samples, so outliers such as
+// spikes do not pull it, and the intercept the median of the residuals
+func TheilSen(seconds, values []float64) (Trend, error) {
+	trend := Trend{SlopePerHour: math.NaN(), R2: math.NaN(), End: math.NaN()}
+	if len(seconds) != len(values) {
+		return trend, fmt.Errorf("Got %d times for %d values", len(seconds), len(values))
+	}
+	var times, samples []float64
+	for i := range values {
+		if !math.IsNaN(values[i]) && !math.IsInf(values[i], 0) {
+			times = append(times, seconds[i])
+			samples = append(samples, values[i])
+		}
+	}
+	times, samples = thin(times, samples, maxTrendSamples)
+	if len(samples) < 2 {
+		return trend, fmt.Errorf("Invalid float slice, need at least 2 values: %g", samples)
+	}
+
+	slopes := make([]float64, 0, len(samples)*(len(samples)-1)/2)
+	for i := range samples {
+		for j := i + 1; j < len(samples); j++ {
+			if times[j] != times[i] {
+				slopes = append(slopes, (samples[j]-samples[i])/(times[j]-times[i]))
+			}
+		}
+	}
+	if len(slopes) == 0 {
+		return trend, fmt.Errorf("All %d samples are at the same time", len(samples))
+	}
+	// Sorting the up to half a million slopes would dominate the fit
+	slope := median(slopes)
+
+	// Times are relative to the first sample to keep their precision
+	first := times[0]
+	residuals := make([]float64, len(samples))
+	for i := range samples {
+		residuals[i] = samples[i] - slope*(times[i]-first)
+	}
+	intercept, _ := percentileSorted(sortedCopy(residuals), 50, PercentileLinear)
+
+	mean := sum(samples) / float64(len(samples))
+	var ssRes, ssTot float64
+	last := first
+	for i := range samples {
+		fit := intercept + slope*(times[i]-first)
+		ssRes += (samples[i] - fit) * (samples[i] - fit)
+		ssTot += (samples[i] - mean) * (samples[i] - mean)
+		last = math.Max(last, times[i])
+	}
+
+	trend.SlopePerHour = slope * 3600
+	if ssTot > 0 {
+		trend.R2 = 1 - ssRes/ssTot
+	}
+	trend.End = intercept + slope*(last-first)
+	return trend, nil
+}
+
+// median returns the linear median of values without sorting them, the values
+// are reordered in place
+func median(values []float64) float64 {
+	k := (len(values) - 1) / 2
+	lower := selectKth(values, k)
+	if len(values)%2 == 1 {
+		return lower
+	}
+	// Every value after k is at least as large once k is selected
+	upper := values[k+1]
+	for _, value := range values[k+2:] {
+		upper = math.Min(upper, value)
+	}
+	return (lower + upper) / 2
+}
+
+// selectKth moves the k-th smallest value to index k with the smaller values
+// before it and the larger ones after it, a quickselect splitting the values
+// equal to the pivot apart so flat series of identical slopes stay linear
+func selectKth(values []float64, k int) float64 {
+	lo, hi := 0, len(values)-1
+	for lo < hi {
+		a, b, c := values[lo], values[lo+(hi-lo)/2], values[hi]
+		pivot := math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+		lt, i, gt := lo, lo, hi
+		for i <= gt {
+			switch {
+			case values[i] < pivot:
+				values[lt], values[i] = values[i], values[lt]
+				lt++
+				i++
+			case values[i] > pivot:
+				values[i], values[gt] = values[gt], values[i]
+				gt--
+			default:
+				i++
+			}
+		}
+		switch {
+		case k < lt:
+			hi = lt - 1
+		case k > gt:
+			lo = gt + 1
+		default:
+			return pivot
+		}
+	}
+	return values[k]
+}
+
+// thin keeps at most max samples evenly spread over a series ordered by time
+func thin(times, values []float64, max int) ([]float64, []float64) {
+	if len(values) <= max {
+		return times, values
+	}
+	thinTimes := make([]float64, max)
+	thinValues := make([]float64, max)
+	step := float64(len(values)-1) / float64(max-1)
+	for i := range thinValues {
+		k := int(math.Round(float64(i) * step))
+		thinTimes[i], thinValues[i] = times[k], values[k]
+	}
+	return thinTimes, thinValues
+}
+
+// Sampler keeps an evenly spread subset of a series as its samples are added,
+// at most twice the samples used by TheilSen, halving them whenever it fills up
+type Sampler struct {
+	times, values []float64
+	stride, count int
+}
+
+// NewSampler returns an empty sampler
+func NewSampler() *Sampler {
+	return &Sampler{stride: 1}
+}
+
+// Add adds a sample at a time in seconds
+func (s *Sampler) Add(seconds, value float64) {
+	if s.count%s.stride == 0 {
+		s.times = append(s.times, seconds)
+		s.values = append(s.values, value)
+	}
+	s.count++
+	if len(s.values) < 2*maxTrendSamples {
+		return
+	}
+	// Keep every other sample and half as many from now on
+	half := len(s.values) / 2
+	for i := 0; i < half; i++ {
+		s.times[i], s.values[i] = s.times[2*i], s.values[2*i]
+	}
+	s.times, s.values = s.times[:half], s.values[:half]
+	s.stride *= 2
+}
+
+// Samples returns the times and values kept
+func (s *Sampler) Samples() (times, values []float64) {
+	return s.times, s.values
+}
+
+// Trend fits a line through the samples kept
+func (s *Sampler) Trend() (Trend, error) {
+	return TheilSen(s.times, s.values)
+}
@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTheilSen(t *testing.T) {
+	// 2 per minute from 100, with a spike the fit ignores
+	var times, values []float64
+	for i := 0; i <= 60; i++ {
+		times = append(times, float64(1500000000+60*i))
+		values = append(values, 100+2*float64(i))
+	}
+	values[30] = 1000
+	trend, err := TheilSen(times, values)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if math.Abs(trend.SlopePerHour-120) > 1e-9 || math.Abs(trend.End-220) > 1e-9 {
+		t.Errorf("Expected a slope of 120 per hour ending at 220, got %+v", trend)
+	}
+	if trend.R2 <= 0 || trend.R2 >= 1 {
+		t.Errorf("Expected the spike to lower R2 below 1, got %v", trend.R2)
+	}
+
+	flat, _ := TheilSen([]float64{0, 1, 2}, []float64{5, 5, 5})
+	if flat.SlopePerHour != 0 || !math.IsNaN(flat.R2) || flat.End != 5 {
+		t.Errorf("Expected a flat trend at 5, got %+v", flat)
+	}
+	if _, err := TheilSen([]float64{0}, []float64{1}); err == nil {
+		t.Errorf("Expected an error for a single sample")
+	}
+	if _, err := TheilSen([]float64{3, 3}, []float64{1, 2}); err == nil {
+		t.Errorf("Expected an error for samples at the same time")
+	}
+}
+
+func TestSelectMedian(t *testing.T) {
+	tests := [][]float64{
+		{3},
+		{2, 1},
+		{5, 1, 4, 2, 3},
+		{7, 7, 7, 7},
+		{1, 9, 1, 9, 1, 9},
+		{-2, 8, 0.5, 8, -2, 3, 3, 100},
+	}
+	random := rand.New(rand.NewSource(1))
+	for n := 0; n < 50; n++ {
+		var values []float64
+		for i := 0; i <= n; i++ {
+			values = append(values, float64(random.Intn(10)))
+		}
+		tests = append(tests, values)
+	}
+	for _, values := range tests {
+		expected, _ := percentileSorted(sortedCopy(values), 50, PercentileLinear)
+		if got := median(append([]float64{}, values...)); got != expected {
+			t.Errorf("Expected median %v of %v, got %v", expected, values, got)
+		}
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := NewSampler()
+	for i := 0; i < 100000; i++ {
+		s.Add(float64(i), 10+float64(i)/3600)
+	}
+	if len(s.values) >= 2*maxTrendSamples || len(s.values) < maxTrendSamples/2 {
+		t.Errorf("Expected the sampler to stay bounded, kept %d samples", len(s.values))
+	}
+	trend, err := s.Trend()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if math.Abs(trend.SlopePerHour-1) > 1e-9 || math.Abs(trend.R2-1) > 1e-9 {
+		t.Errorf("Expected a slope of 1 per hour fitting exactly, got %+v", trend)
+	}
+}
@@ -6,13 +6,14 @@ import (
 	"fmt"
 	"os"
 	"regexp"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/openshift-scale/perf-analyzer/pkg/result"
 )
 
 // WriteCSV will write the result data to a CSV file
-func WriteCSV(resultDir string, keys []string, fileHeader map[string][]string, hosts []result.Host) error {
+func WriteCSV(resultDir string, hosts []result.Host) error {
 	csvFile, err := os.Create(resultDir + "out.csv")
 	if err != nil {
 		return err
@@ -24,13 +25,12 @@ func WriteCSV(resultDir string, keys []string, fileHeader map[string][]string, h
 	defer writer.Flush()
 
 	// Create header & write
-	header := createHeaders(keys, fileHeader)
+	header := createHeaders(hosts)
 	for _, h := range header {
 		writer.Write(h)
 	}
 
-	// TODO: Maybe use reflection to get these fields instead
-	stats := []string{"min", "mean", "p95", "max"}
+	stats := result.StatNames(hosts)
 	// Write all stats
 	for _, v := range stats {
 		writer.Write([]string{v})
@@ -42,6 +42,45 @@ func WriteCSV(resultDir string, keys []string, fileHeader map[string][]string, h
 	return nil
 }
 
+// WriteHistograms will write the histogram of every result to a CSV file, one
+// row per bucket with its bounds, count and fraction of the samples
+func WriteHistograms(resultDir string, hosts []result.Host) error {
+	csvFile, err := os.Create(resultDir + "histograms.csv")
+	if err != nil {
+		return err
+	}
+	defer csvFile.Close()
+
+	writer := csv.NewWriter(csvFile)
+	defer writer.Flush()
+
+	writer.Write([]string{"host", "resource", "kind", "phase", "lower", "upper", "count", "fraction"})
+	for _, h := range hosts {
+		for _, r := range h.Results {
+			if r.Histogram == nil {
+				continue
+			}
+			total := float64(r.Histogram.Total())
+			row := func(lower, upper string, count uint64) {
+				fraction := strconv.FormatFloat(float64(count)/total, 'f', 4, 64)
+				writer.Write([]string{h.Kind, r.Resource, r.Kind, r.Phase, lower, upper, strconv.FormatUint(count, 10), fraction})
+			}
+			// The values of at most 0 of a log histogram have no lower bound
+			if r.Histogram.Zero > 0 {
+				row("", "0", r.Histogram.Zero)
+			}
+			for i, count := range r.Histogram.Counts {
+				row(formatBound(r.Histogram.Bounds[i]), formatBound(r.Histogram.Bounds[i+1]), count)
+			}
+		}
+	}
+	return writer.Error()
+}
+
+func formatBound(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
 // ReadCSV will return a 2d slice containing the CSV data
 func ReadCSV(file string) ([][]string, error) {
 	fmt.Println(file)
@@ -60,20 +99,79 @@ func ReadCSV(file string) ([][]string, error) {
 	return result, nil
 }
 
-func createHeaders(keys []string, fileHeader map[string][]string) (header [][]string) {
+// CSVReader reads a CSV file one row at a time
+type CSVReader struct {
+	*csv.Reader
+	file *os.File
+}
+
+// OpenCSV will open a CSV file to read it row by row instead of all at once
+func OpenCSV(file string) (*CSVReader, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVReader{Reader: csv.NewReader(bufio.NewReader(f)), file: f}, nil
+}
+
+// Close closes the CSV file
+func (r *CSVReader) Close() error {
+	return r.file.Close()
+}
+
+// ReadTimeline will read phases from a CSV of name, start and end, where
+// times are RFC3339 or milliseconds since the epoch
+func ReadTimeline(file string) ([]result.Phase, error) {
+	rows, err := ReadCSV(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var phases []result.Phase
+	for i, row := range rows {
+		if len(row) < 3 {
+			return nil, fmt.Errorf("Timeline row %d needs name, start and end: %v", i+1, row)
+		}
+		start, err := parseTime(row[1])
+		if err != nil {
+			// Allow a header row
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("Timeline row %d: %v", i+1, err)
+		}
+		end, err := parseTime(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("Timeline row %d: %v", i+1, err)
+		}
+		phases = append(phases, result.Phase{Name: row[0], Window: result.Window{Start: start, End: end}})
+	}
+	return phases, nil
+}
+
+// parseTime parses an RFC3339 time or milliseconds since the epoch
+func parseTime(value string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, ms*int64(time.Millisecond)), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// createHeaders uses the results of the first host to name the columns
+func createHeaders(hosts []result.Host) (header [][]string) {
 	empty := []string{""}
 	header = append(header, empty)
 	header = append(header, empty)
-	for _, key := range keys {
-		// header keys are filenames, so we want to truncate the extension
-		k := strings.Split(key, ".")
-		for i := 0; i < len(fileHeader[key]); i++ {
-			header[0] = append(header[0], k[0])
-		}
-		for _, head := range fileHeader[key] {
-			header[1] = append(header[1], cleanWord(head))
-
+	if len(hosts) == 0 {
+		return
+	}
+	for _, r := range hosts[0].Results {
+		if r.Phase != "" {
+			header[0] = append(header[0], r.Resource+":"+r.Phase)
+		} else {
+			header[0] = append(header[0], r.Resource)
 		}
+		header[1] = append(header[1], cleanWord(r.Kind))
 	}
 	return
 }
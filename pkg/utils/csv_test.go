@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadTimeline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := writeFile(t, dir, "timeline.csv", "name,start,end\nramp,2019-10-01T10:00:00Z,2019-10-01T10:20:00Z\nsteady,1569925200000,1569927000000\n")
+	phases, err := ReadTimeline(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(phases) != 2 || phases[0].Name != "ramp" || phases[1].Name != "steady" {
+		t.Fatalf("Expected the ramp and steady phases, got %+v", phases)
+	}
+	start := time.Date(2019, 10, 1, 10, 20, 0, 0, time.UTC)
+	if !phases[0].End.Equal(start) || !phases[1].Start.Equal(start) || !phases[1].End.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("Expected RFC3339 and millisecond times, got %+v", phases)
+	}
+
+	invalid := map[string]string{
+		"short.csv":   "ramp,2019-10-01T10:00:00Z\n",
+		"start.csv":   "ramp,2019-10-01T10:00:00Z,2019-10-01T10:20:00Z\nsteady,soon,2019-10-01T10:50:00Z\n",
+		"end.csv":     "ramp,2019-10-01T10:00:00Z,later\n",
+		"missing.csv": "",
+	}
+	for name, content := range invalid {
+		file := filepath.Join(dir, name)
+		if content != "" {
+			file = writeFile(t, dir, name, content)
+		}
+		if _, err := ReadTimeline(file); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/openshift-scale/perf-analyzer/pkg/result"
+)
+
+// ReadRunInfo will parse the pbench metadata.log of a run directory
+func ReadRunInfo(runDir string) (*result.RunInfo, error) {
+	sections, err := readINI(filepath.Join(runDir, "metadata.log"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.RunInfo{
+		Name:       first(sections["pbench"]["name"], sections["run"]["name"]),
+		Controller: first(sections["run"]["controller"], sections["controller"]["hostname"]),
+		Date:       first(sections["pbench"]["date"], sections["run"]["start_run"]),
+		Config:     first(sections["pbench"]["config"], sections["run"]["config"]),
+	}, nil
+}
+
+// sysinfoFiles are the names of the sysinfo files read by ReadHostInfo, without their extension
+var sysinfoFiles = map[string]bool{"lscpu": true, "meminfo": true, "uname": true, "os-release": true}
+
+// ReadHostInfo will search the sysinfo directories for the lscpu, meminfo, uname and
+// os-release output collected by pbench sysinfo, files that are not found are skipped
+func ReadHostInfo(dirs ...string) *result.HostInfo {
+	var info result.HostInfo
+	found := false
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+			if err != nil || f.IsDir() {
+				return nil
+			}
+			// Only the few sysinfo files are read, a directory may hold large tool outputs
+			name := strings.TrimSuffix(strings.TrimSuffix(f.Name(), ".txt"), ".log")
+			if !sysinfoFiles[name] {
+				return nil
+			}
+			lines, err := readLines(path)
+			if err != nil {
+				return nil
+			}
+			switch name {
+			case "lscpu":
+				found = true
+				info.CPUModel = first(info.CPUModel, field(lines, "Model name", ":"))
+				if info.Cores == 0 {
+					info.Cores, _ = strconv.Atoi(field(lines, "CPU(s)", ":"))
+				}
+			case "meminfo":
+				found = true
+				if info.MemoryKB == 0 {
+					info.MemoryKB, _ = strconv.ParseInt(strings.TrimSuffix(field(lines, "MemTotal", ":"), " kB"), 10, 64)
+				}
+			case "uname":
+				found = true
+				// uname -a output has the kernel release as third field
+				if fields := strings.Fields(strings.Join(lines, " ")); info.Kernel == "" && len(fields) > 2 {
+					info.Kernel = fields[2]
+				}
+			case "os-release":
+				found = true
+				info.OS = first(info.OS, strings.Trim(field(lines, "PRETTY_NAME", "="), `"`))
+			}
+			return nil
+		})
+	}
+	if !found {
+		return nil
+	}
+	return &info
+}
+
+// readINI will read a file of [section] and key = value lines
+func readINI(file string) (map[string]map[string]string, error) {
+	lines, err := readLines(file)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := map[string]map[string]string{}
+	section := ""
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		default:
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				kv = strings.SplitN(line, ":", 2)
+			}
+			if len(kv) == 2 {
+				if sections[section] == nil {
+					sections[section] = map[string]string{}
+				}
+				sections[section][strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+			}
+		}
+	}
+	return sections, nil
+}
+
+// field returns the trimmed value of the first "key<sep>value" line
+func field(lines []string, key, sep string) string {
+	for _, line := range lines {
+		kv := strings.SplitN(line, sep, 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == key {
+			return strings.TrimSpace(kv[1])
+		}
+	}
+	return ""
+}
+
+// first returns the first non-empty string
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func readLines(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadRunInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "metadata.log", "# comment\n[pbench]\nname = uperf_run\ndate: 2019-10-01T10:00:00\n\n[run]\ncontroller = ctrl.example.com\nconfig=nightly\nname = ignored\n")
+	info, err := ReadRunInfo(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if info.Name != "uperf_run" || info.Controller != "ctrl.example.com" || info.Date != "2019-10-01T10:00:00" || info.Config != "nightly" {
+		t.Errorf("Unexpected run info %+v", info)
+	}
+	if _, err := ReadRunInfo(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("Expected an error without metadata.log")
+	}
+}
+
+func TestReadINI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := writeFile(t, dir, "test.ini", "top = 1\n; comment\n[ section ]\nkey: a=b\nbroken\n")
+	sections, err := readINI(file)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if sections[""]["top"] != "1" || sections["section"]["key: a"] != "b" || len(sections["section"]) != 1 {
+		t.Errorf("Unexpected sections %v", sections)
+	}
+}
+
+func TestReadHostInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sysinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	beg := filepath.Join(dir, "sysinfo", "beg", "svt-node-1")
+	writeFile(t, beg, "lscpu.txt", "Architecture: x86_64\nCPU(s): 8\nModel name: Intel Xeon\n")
+	writeFile(t, beg, "meminfo.txt", "MemTotal:       16384 kB\nMemFree: 1 kB\n")
+	writeFile(t, beg, "uname.txt", "Linux svt-node-1 3.10.0-693.el7.x86_64 #1 SMP x86_64 GNU/Linux\n")
+	writeFile(t, beg, "etc/os-release", "NAME=\"RHEL\"\nPRETTY_NAME=\"Red Hat Enterprise Linux 7.4\"\n")
+	end := filepath.Join(dir, "sysinfo", "end", "svt-node-1")
+	writeFile(t, end, "lscpu.txt", "CPU(s): 4\nModel name: Other\n")
+
+	info := ReadHostInfo(filepath.Join(dir, "missing"), beg, end)
+	if info == nil {
+		t.Fatalf("Expected host info")
+	}
+	// The first directory with a value wins
+	if info.CPUModel != "Intel Xeon" || info.Cores != 8 || info.MemoryKB != 16384 || info.Kernel != "3.10.0-693.el7.x86_64" || info.OS != "Red Hat Enterprise Linux 7.4" {
+		t.Errorf("Unexpected host info %+v", info)
+	}
+
+	other := filepath.Join(dir, "other")
+	writeFile(t, other, "pidstat/csv/cpu_usage_percent_cpu.csv", "timestamp_ms,1-etcd\n")
+	if info := ReadHostInfo(other); info != nil {
+		t.Errorf("Expected no host info without sysinfo files, got %+v", info)
+	}
+}